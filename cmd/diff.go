@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/dirtree"
+	"github.com/Maxim-Ba/dir-tree/formatter"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// Exit codes of the diff command, following diff(1)
+const (
+	exitSame      = 0
+	exitDifferent = 1
+	exitTrouble   = 2
+)
+
+// runDiff compares two directories or saved trees and writes the changes
+// to stdout, returning the exit code
+func runDiff(args []string) int {
+	cfg, err := configs.ParseDiffConfig(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSame
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing diff arguments: %v\n", err)
+		return exitTrouble
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config validation failed: %v\n", err)
+		return exitTrouble
+	}
+
+	old, current, changes, diffErr := dirtree.Diff(context.Background(), cfg.Old, cfg.New, &cfg.Scan)
+	var partialErr *tree.PartialError
+	if diffErr != nil && !errors.As(diffErr, &partialErr) {
+		fmt.Fprintf(os.Stderr, "Error comparing trees: %v\n", diffErr)
+		return exitTrouble
+	}
+
+	colors := configs.FormatCfg{Color: cfg.Color}
+	formatter.ResolveColor(&colors, os.Stdout)
+	output, err := formatter.FormatDiff(old, current, changes, cfg.Format, colors.Color)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error formatting diff: %v\n", err)
+		return exitTrouble
+	}
+	if _, err := os.Stdout.Write(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing diff: %v\n", err)
+		return exitTrouble
+	}
+
+	// The changes of partial trees are written, but are not conclusive
+	if partialErr != nil {
+		printSummary(os.Stderr, partialErr)
+		return exitTrouble
+	}
+	if len(changes) > 0 {
+		return exitDifferent
+	}
+	return exitSame
+}
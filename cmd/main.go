@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 
 	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/dirtree"
 	"github.com/Maxim-Ba/dir-tree/formatter"
 	"github.com/Maxim-Ba/dir-tree/tree"
 )
 
+// exitIncomplete is the exit code used when the tree was written but
+// some paths could not be read
+const exitIncomplete = 3
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "update" {
+		os.Exit(runUpdate(os.Args[2:]))
+	}
 
 	cfg, err := configs.ParseConfig()
 	if err != nil {
@@ -23,19 +37,50 @@ func main() {
 	}
 
 
-	root, err := tree.BuildTree(
-		tree.BuildOptions{Path: cfg.Path,
-			MaxDepth:     cfg.MaxDepth,
-			ExcludePaths: cfg.ExcludePaths,
-			ExcludeTypes: cfg.ExcludePaths,
-			IncludeFiles: cfg.IncludeFiles,
-			FollowLinks:  cfg.FollowLinks,
-		})
+	opts, err := cfg.BuildOptions()
 	if err != nil {
-		log.Fatalf("Error building tree: %v", err)
+		log.Fatalf("Config validation failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	// Colors only make sense on a terminal, never in a file
+	if cfg.Format.OutputPath != "" {
+		cfg.Format.Color = configs.ColorNever
+	}
+	formatter.ResolveColor(&cfg.Format, os.Stdout)
+
+	if cfg.Watch {
+		if err := watchOutput(ctx, cfg, opts); err != nil {
+			log.Fatalf("Error watching tree: %v", err)
+		}
+		return
+	}
+
+	if cfg.Stream {
+		streamErr := streamOutput(ctx, cfg)
+		var partialErr *tree.PartialError
+		if streamErr != nil && !errors.As(streamErr, &partialErr) {
+			log.Fatalf("Error streaming tree: %v", streamErr)
+		}
+		if partialErr != nil {
+			printSummary(os.Stderr, partialErr)
+			os.Exit(exitIncomplete)
+		}
+		return
+	}
+
+	root, buildErr := tree.BuildTreeContext(ctx, opts)
+	var partialErr *tree.PartialError
+	if buildErr != nil && !errors.As(buildErr, &partialErr) {
+		log.Fatalf("Error building tree: %v", buildErr)
 	}
 
-	
 	formattedOutput, err := formatter.Format(root, &cfg.Format)
 	if err != nil {
 		log.Fatalf("Error formatting tree: %v", err)
@@ -45,20 +90,56 @@ func main() {
 		log.Fatalf("Error saving output: %v", err)
 	}
 
+	// The partial tree has been written, but the run still failed
+	if partialErr != nil {
+		printSummary(os.Stderr, partialErr)
+		os.Exit(exitIncomplete)
+	}
+
+}
+// printSummary reports the paths missing from an incomplete tree
+func printSummary(w io.Writer, partialErr *tree.PartialError) {
+	fmt.Fprintf(w, "Tree is incomplete: %v\n", partialErr)
+	for _, failure := range partialErr.Failures {
+		fmt.Fprintf(w, "  %v\n", failure)
+	}
+	for _, path := range partialErr.Unscanned {
+		fmt.Fprintf(w, "  %s: not read\n", path)
+	}
+}
+
+// streamOutput writes the tree to the output file, or stdout, while it
+// is scanned
+func streamOutput(ctx context.Context, cfg *configs.Config) error {
+	outputPath := cfg.Format.GetOutputPath()
+	if outputPath == "" {
+		return dirtree.StreamContext(ctx, os.Stdout, cfg)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	err = dirtree.StreamContext(ctx, file, cfg)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	var partialErr *tree.PartialError
+	if err == nil || errors.As(err, &partialErr) {
+		fmt.Printf("Tree successfully written to: %s\n", outputPath)
+	}
+	return err
 }
+
 func saveOutput(data []byte, format *configs.FormatCfg) error {
-	outputPath := format.OutputPath
+	outputPath := format.GetOutputPath()
 	if outputPath == "" {
 		// Вывод в stdout
 		fmt.Println(string(data))
 		return nil
 	}
 
-	ext := fmt.Sprintf(".%s", string(format.Type))
-	if !hasExtension(outputPath, ext) {
-		outputPath += ext
-	}
-
 	err := os.WriteFile(outputPath, data, 0644)
 	if err != nil {
 		return err
@@ -67,7 +148,3 @@ func saveOutput(data []byte, format *configs.FormatCfg) error {
 	fmt.Printf("Tree successfully written to: %s\n", outputPath)
 	return nil
 }
-
-func hasExtension(path, ext string) bool {
-	return len(path) >= len(ext) && path[len(path)-len(ext):] == ext
-}
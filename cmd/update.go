@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/dirtree"
+)
+
+// exitStale is the exit code of the update command when a tree is out of
+// date in check mode
+const exitStale = 1
+
+// runUpdate regenerates the trees embedded in Markdown documents, or
+// checks that they are current, returning the exit code
+func runUpdate(args []string) int {
+	cfg, err := configs.ParseUpdateConfig(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSame
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing update arguments: %v\n", err)
+		return exitTrouble
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config validation failed: %v\n", err)
+		return exitTrouble
+	}
+
+	code := exitSame
+	for _, path := range cfg.Files {
+		changed, err := dirtree.UpdateFile(context.Background(), path, cfg.Check)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitTrouble
+		case changed && cfg.Check:
+			fmt.Fprintf(os.Stderr, "%s: tree is out of date, run dir-tree update %s\n", path, path)
+			code = exitStale
+		case changed:
+			fmt.Printf("Updated %s\n", path)
+		}
+	}
+	return code
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/formatter"
+	"github.com/Maxim-Ba/dir-tree/tree"
+	"github.com/Maxim-Ba/dir-tree/watch"
+)
+
+// clearScreen moves the cursor home and clears a terminal
+const clearScreen = "\x1b[H\x1b[2J"
+
+// watchOutput writes the tree and keeps it up to date as the file system
+// changes, until interrupted. The output file is rewritten, or the
+// terminal redrawn; with events enabled, change events are written to
+// stdout instead of the tree.
+func watchOutput(ctx context.Context, cfg *configs.Config, opts tree.BuildOptions) error {
+	w, err := watch.New(ctx, watch.Options{BuildOptions: opts, Debounce: cfg.Debounce})
+	var partialErr *tree.PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		return err
+	}
+	defer w.Close()
+	if partialErr != nil {
+		printSummary(os.Stderr, partialErr)
+	}
+
+	outputPath := cfg.Format.GetOutputPath()
+	redraw := isTerminal(os.Stdout)
+	render := func(root *tree.Node) error {
+		data, err := formatter.Format(root, &cfg.Format)
+		if err != nil {
+			return fmt.Errorf("error formatting tree: %w", err)
+		}
+		if outputPath != "" {
+			return os.WriteFile(outputPath, data, 0644)
+		}
+		if redraw {
+			fmt.Print(clearScreen)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if !cfg.Events || outputPath != "" {
+		if err := render(w.Root()); err != nil {
+			return err
+		}
+	}
+	if outputPath != "" && !cfg.Events {
+		fmt.Printf("Watching %s, writing to: %s\n", opts.Path, outputPath)
+	}
+
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	events := json.NewEncoder(os.Stdout)
+	err = w.Run(runCtx, func(root *tree.Node, changes []watch.Event) error {
+		if cfg.Events {
+			for _, event := range changes {
+				if err := events.Encode(event); err != nil {
+					return err
+				}
+			}
+			if outputPath == "" {
+				return nil
+			}
+		}
+		return render(root)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
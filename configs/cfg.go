@@ -2,25 +2,101 @@ package configs
 
 import (
 	"fmt"
+	"runtime"
 	"strings"
+	"time"
+
+	"github.com/Maxim-Ba/dir-tree/tree"
 )
 
 // OutputFormat represents supported output formats
 type OutputFormat string
 
 const (
-	JSON OutputFormat = "json" // JSON format
-	YAML OutputFormat = "yaml" // YAML format
-	XML  OutputFormat = "xml"  // XML format
-	TXT  OutputFormat = "txt"  // Plain text format
+	JSON     OutputFormat = "json"     // JSON format
+	YAML     OutputFormat = "yaml"     // YAML format
+	XML      OutputFormat = "xml"      // XML format
+	TXT      OutputFormat = "txt"      // Plain text format
+	NDJSON   OutputFormat = "ndjson"   // One JSON object per line and node
+	Markdown OutputFormat = "markdown" // Markdown tree, bullet list or table, see MarkdownVariant
+	HTML     OutputFormat = "html"     // Standalone interactive HTML report
+	SVG      OutputFormat = "svg"      // Treemap or sunburst chart of sizes, see SVGChart
+	DOT      OutputFormat = "dot"      // Graphviz digraph of the hierarchy
+)
+
+// SVGChart represents the charts of the SVG format, selected with its
+// "chart" option
+type SVGChart string
+
+const (
+	SVGTreemap  SVGChart = "treemap"  // Squarified treemap of nested rectangles
+	SVGSunburst SVGChart = "sunburst" // Rings of arcs, one per level
+)
+
+// MarkdownVariant represents the layouts of the Markdown format, selected
+// with its "variant" option
+type MarkdownVariant string
+
+const (
+	MarkdownTree  MarkdownVariant = "tree"  // Fenced code block with a box-drawn tree
+	MarkdownList  MarkdownVariant = "list"  // Nested bullet list, optionally linking to each path
+	MarkdownTable MarkdownVariant = "table" // Table of path, type, size and modification time
+)
+
+// TXTStyle represents the supported styles of the TXT format
+type TXTStyle string
+
+const (
+	EmojiStyle   TXTStyle = "emoji"   // Indentation with emoji type markers
+	UnicodeStyle TXTStyle = "unicode" // tree(1) box-drawing connectors
+	ASCIIStyle   TXTStyle = "ascii"   // tree(1) connectors using ASCII only
+	PlainStyle   TXTStyle = "plain"   // Indentation only
+)
+
+// ColorMode represents when TXT output is colored
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"   // Color when writing to a terminal and NO_COLOR is unset
+	ColorAlways ColorMode = "always" // Always color
+	ColorNever  ColorMode = "never"  // Never color
+)
+
+// SizeFormat represents how sizes are presented: raw bytes, scaled SI or
+// IEC units, or one of the fixed units in SizeUnits
+type SizeFormat string
+
+const (
+	BytesSize SizeFormat = "bytes" // Raw byte counts
+	SISize    SizeFormat = "si"    // Powers of 1000: kB, MB, GB...
+	IECSize   SizeFormat = "iec"   // Powers of 1024: KiB, MiB, GiB...
 )
 
+// SizeUnits maps the fixed units a SizeFormat may name to their size in bytes
+var SizeUnits = map[SizeFormat]int64{
+	"B":   1,
+	"kB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
 // FormatCfg contains formatting configuration options
 type FormatCfg struct {
-	Type             OutputFormat `json:"type" yaml:"type"`                             // Output format type
-	OutputPath       string       `json:"output_path" yaml:"output_path"`               // Output file path (without extension)
-	Indent           int          `json:"indent" yaml:"indent"`                         // Indentation for pretty formatting
-	ExcludeNodeFields []string    `json:"exclude_node_fields" yaml:"exclude_node_fields"` // Node fields to exclude from output
+	Type              OutputFormat      `json:"type" yaml:"type"`                               // Output format type
+	OutputPath        string            `json:"output_path" yaml:"output_path"`                 // Output file path (without extension)
+	Indent            int               `json:"indent" yaml:"indent"`                           // Indentation for pretty formatting
+	ExcludeNodeFields []string          `json:"exclude_node_fields" yaml:"exclude_node_fields"` // Node fields to exclude from output
+	DiskUsage         bool              `json:"disk_usage" yaml:"disk_usage"`                   // Show disk usage instead of apparent size, like du
+	Style             TXTStyle          `json:"style" yaml:"style"`                             // TXT style: emoji, unicode, ascii or plain
+	Color             ColorMode         `json:"color" yaml:"color"`                             // When to color TXT names using LS_COLORS
+	SizeFormat        SizeFormat        `json:"size_format" yaml:"size_format"`                 // Size presentation: bytes, si, iec or a fixed unit such as MiB
+	Percent           bool              `json:"percent" yaml:"percent"`                         // Show each node's share of its parent's size
+	Options           map[string]string `json:"options" yaml:"options"`                         // Format specific options, see FormatDescriptor
 }
 
 // GetOutputPath returns the output path with appropriate file extension
@@ -29,8 +105,11 @@ func (f *FormatCfg) GetOutputPath() string {
         return "" // indicates stdout output
     }
     
-    // Add extension if missing
+    // Add extension if missing, as declared by the registered format
     ext := fmt.Sprintf(".%s", string(f.Type))
+    if descriptor, ok := LookupFormat(f.Type); ok && descriptor.Extension != "" {
+        ext = "." + descriptor.Extension
+    }
     if !hasExtension(f.OutputPath, ext) {
         return f.OutputPath + ext
     }
@@ -39,13 +118,33 @@ func (f *FormatCfg) GetOutputPath() string {
 
 // Config contains all configuration options for directory tree generation
 type Config struct {
-	Path         string    `json:"path" yaml:"path"`                   // Root directory path
-	ExcludeTypes []string  `json:"exclude_types" yaml:"exclude_types"` // File extensions to exclude (e.g., [".tmp", ".log"])
-	ExcludePaths []string  `json:"exclude_paths" yaml:"exclude_paths"` // Path patterns to exclude (regex)
-	IncludeFiles bool      `json:"include_files" yaml:"include_files"` // Whether to include files or only directories
-	MaxDepth     int       `json:"max_depth" yaml:"max_depth"`         // Maximum traversal depth (-1 for unlimited)
-	FollowLinks  bool      `json:"follow_links" yaml:"follow_links"`   // Whether to follow symbolic links
-	Format       FormatCfg `json:"format" yaml:"format"`               // Formatting configuration
+	Path            string        `json:"path" yaml:"path"`                         // Root directory path
+	ExcludeTypes    []string      `json:"exclude_types" yaml:"exclude_types"`       // File extensions to exclude (e.g., [".tmp", ".log"])
+	ExcludePaths    []string      `json:"exclude_paths" yaml:"exclude_paths"`       // Path patterns to exclude (regex)
+	Exclude         []string      `json:"exclude" yaml:"exclude"`                   // Glob, doublestar or regex patterns to exclude, relative to the root
+	Include         []string      `json:"include" yaml:"include"`                   // Glob, doublestar or regex patterns files must match to be included
+	Filter          string        `json:"filter" yaml:"filter"`                     // find(1)-style filter expression, e.g. "-type f -size +100M"
+	IncludeFiles    bool          `json:"include_files" yaml:"include_files"`       // Whether to include files or only directories
+	MaxDepth        int           `json:"max_depth" yaml:"max_depth"`               // Maximum traversal depth (-1 for unlimited)
+	FollowLinks     bool          `json:"follow_links" yaml:"follow_links"`         // Whether to follow symbolic links
+	ConfineLinks    bool          `json:"confine_links" yaml:"confine_links"`       // Whether to refuse following links that leave the root
+	CollectMetadata bool          `json:"collect_metadata" yaml:"collect_metadata"` // Whether to collect times, permissions, ownership and inodes
+	Hash            string        `json:"hash" yaml:"hash"`                         // Content digest of files: sha256, sha1 or md5 (empty for none)
+	DedupHardLinks  bool          `json:"dedup_hard_links" yaml:"dedup_hard_links"` // Whether directory totals count hard-linked files once
+	GitIgnore       bool          `json:"git_ignore" yaml:"git_ignore"`             // Whether to skip paths ignored by git
+	DirtreeIgnore   bool          `json:"dirtree_ignore" yaml:"dirtree_ignore"`     // Whether to skip paths matched by .dirtreeignore files
+	Sort            string        `json:"sort" yaml:"sort"`                         // Child order: name, natural, ignore-case, size, mtime or extension
+	SortDescending  bool          `json:"sort_descending" yaml:"sort_descending"`   // Whether to reverse the sort order
+	DirsFirst       bool          `json:"dirs_first" yaml:"dirs_first"`             // Whether to list directories before files
+	FilesFirst      bool          `json:"files_first" yaml:"files_first"`           // Whether to list files before directories
+	ErrorPolicy     string        `json:"error_policy" yaml:"error_policy"`         // What to do with unreadable paths: abort, skip or record
+	Concurrency     int           `json:"concurrency" yaml:"concurrency"`           // Maximum number of directories scanned in parallel
+	Timeout         time.Duration `json:"timeout" yaml:"timeout"`                   // Maximum scan duration (0 for unlimited)
+	Stream          bool          `json:"stream" yaml:"stream"`                     // Whether to write nodes while scanning instead of building the tree first
+	Watch           bool          `json:"watch" yaml:"watch"`                       // Whether to keep updating the output as the file system changes
+	Debounce        time.Duration `json:"debounce" yaml:"debounce"`                 // Quiet period before changes are applied in watch mode (0 for the default)
+	Events          bool          `json:"events" yaml:"events"`                     // Whether to write change events as NDJSON to stdout in watch mode
+	Format          FormatCfg     `json:"format" yaml:"format"`                     // Formatting configuration
 }
 
 // Validate checks if the configuration is valid
@@ -58,16 +157,174 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max depth cannot be less than -1")
 	}
 
-	switch c.Format.Type {
-	case JSON, YAML, XML, TXT:
-		// valid formats
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency cannot be negative")
+	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+
+	if c.Debounce < 0 {
+		return fmt.Errorf("debounce cannot be negative")
+	}
+
+	if c.Watch && c.Stream {
+		return fmt.Errorf("watch mode cannot be combined with streaming")
+	}
+
+	if c.Events && !c.Watch {
+		return fmt.Errorf("events are only written in watch mode")
+	}
+
+	switch tree.ErrorPolicy(c.ErrorPolicy) {
+	case "", tree.AbortOnError, tree.SkipOnError, tree.RecordOnError:
+		// valid policies
+	default:
+		return fmt.Errorf("unsupported error policy: %s", c.ErrorPolicy)
+	}
+
+	sortBy, err := tree.ParseSortKey(c.Sort)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tree.ParseHashAlgorithm(c.Hash); err != nil {
+		return err
+	}
+
+	if c.DirsFirst && c.FilesFirst {
+		return fmt.Errorf("dirs first and files first cannot be combined")
+	}
+
+	if _, err := parsePatterns(c.Exclude); err != nil {
+		return fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
+	if _, err := parsePatterns(c.Include); err != nil {
+		return fmt.Errorf("invalid include pattern: %w", err)
+	}
+
+	if _, err := parseFilter(c.Filter); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+
+	if err := c.Format.validateFormat(); err != nil {
+		return err
+	}
+
+	if c.Stream {
+		if descriptor, _ := LookupFormat(c.Format.Type); !descriptor.Streaming {
+			return fmt.Errorf("output format %s does not support streaming", c.Format.Type)
+		}
+		if sortBy == tree.SortBySize {
+			return fmt.Errorf("sorting by size is not supported while streaming")
+		}
+		if c.Format.Percent {
+			return fmt.Errorf("percentages are not supported while streaming")
+		}
+	}
+
+	switch c.Format.Style {
+	case "", EmojiStyle, UnicodeStyle, ASCIIStyle, PlainStyle:
+		// valid styles
+	default:
+		return fmt.Errorf("unsupported txt style: %s", c.Format.Style)
+	}
+
+	if _, fixed := SizeUnits[c.Format.SizeFormat]; !fixed {
+		switch c.Format.SizeFormat {
+		case "", BytesSize, SISize, IECSize:
+			// valid size formats
+		default:
+			return fmt.Errorf("unsupported size format: %s", c.Format.SizeFormat)
+		}
+	}
+
+	switch c.Format.Color {
+	case "", ColorAuto, ColorAlways, ColorNever:
+		// valid modes
 	default:
-		return fmt.Errorf("unsupported output format: %s", c.Format.Type)
+		return fmt.Errorf("unsupported color mode: %s", c.Format.Color)
 	}
 
 	return nil
 }
 
+// BuildOptions converts the configuration into tree build options
+func (c *Config) BuildOptions() (tree.BuildOptions, error) {
+	exclude, err := parsePatterns(c.Exclude)
+	if err != nil {
+		return tree.BuildOptions{}, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	include, err := parsePatterns(c.Include)
+	if err != nil {
+		return tree.BuildOptions{}, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	filter, err := parseFilter(c.Filter)
+	if err != nil {
+		return tree.BuildOptions{}, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	sortBy, err := tree.ParseSortKey(c.Sort)
+	if err != nil {
+		return tree.BuildOptions{}, err
+	}
+	// Sizes are sorted by the measure they are shown in
+	if sortBy == tree.SortBySize && c.Format.DiskUsage {
+		sortBy = tree.SortByDiskUsage
+	}
+	hash, err := tree.ParseHashAlgorithm(c.Hash)
+	if err != nil {
+		return tree.BuildOptions{}, err
+	}
+
+	return tree.BuildOptions{
+		Path:            c.Path,
+		MaxDepth:        c.MaxDepth,
+		ExcludePaths:    c.ExcludePaths,
+		ExcludeTypes:    c.ExcludeTypes,
+		Exclude:         exclude,
+		Include:         include,
+		Filter:          filter,
+		IncludeFiles:    c.IncludeFiles,
+		FollowLinks:     c.FollowLinks,
+		ConfineLinks:    c.ConfineLinks,
+		CollectMetadata: c.CollectMetadata,
+		Hash:            hash,
+		DedupHardLinks:  c.DedupHardLinks,
+		GitIgnore:       c.GitIgnore,
+		DirtreeIgnore:   c.DirtreeIgnore,
+		SortBy:          sortBy,
+		SortDescending:  c.SortDescending,
+		DirsFirst:       c.DirsFirst,
+		FilesFirst:      c.FilesFirst,
+		ErrorPolicy:     tree.ErrorPolicy(c.ErrorPolicy),
+		Concurrency:     c.Concurrency,
+	}, nil
+}
+
+// parsePatterns parses typed pattern strings such as "glob:*.go"
+func parsePatterns(patterns []string) ([]tree.Pattern, error) {
+	result := make([]tree.Pattern, 0, len(patterns))
+	for _, s := range patterns {
+		p, err := tree.ParsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// parseFilter parses a filter expression, returning nil if it is empty
+func parseFilter(expr string) (*tree.Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	return tree.ParseFilter(expr)
+}
+
 // New creates a new ConfigBuilder with default values
 func New() *ConfigBuilder {
     return &ConfigBuilder{
@@ -76,6 +333,7 @@ func New() *ConfigBuilder {
             MaxDepth:     1,
             IncludeFiles: true,
             FollowLinks:  false,
+            Concurrency:  runtime.NumCPU(),
             Format: FormatCfg{
                 Type:       JSON,
                 OutputPath: "output-dir",
@@ -115,6 +373,103 @@ func (b *ConfigBuilder) WithFollowLinks(followLinks bool) *ConfigBuilder {
     return b
 }
 
+// WithConfineLinks sets whether followed links must stay inside the root
+func (b *ConfigBuilder) WithConfineLinks(confineLinks bool) *ConfigBuilder {
+    b.config.ConfineLinks = confineLinks
+    return b
+}
+
+// WithCollectMetadata sets whether node metadata is collected
+func (b *ConfigBuilder) WithCollectMetadata(collectMetadata bool) *ConfigBuilder {
+    b.config.CollectMetadata = collectMetadata
+    return b
+}
+
+// WithHash sets the algorithm used to digest file contents
+func (b *ConfigBuilder) WithHash(hash string) *ConfigBuilder {
+    b.config.Hash = hash
+    return b
+}
+
+// WithDedupHardLinks sets whether directory totals count hard-linked files once
+func (b *ConfigBuilder) WithDedupHardLinks(dedupHardLinks bool) *ConfigBuilder {
+    b.config.DedupHardLinks = dedupHardLinks
+    return b
+}
+
+// WithGitIgnore sets whether paths ignored by git are skipped
+func (b *ConfigBuilder) WithGitIgnore(gitIgnore bool) *ConfigBuilder {
+    b.config.GitIgnore = gitIgnore
+    return b
+}
+
+// WithDirtreeIgnore sets whether paths matched by .dirtreeignore files are skipped
+func (b *ConfigBuilder) WithDirtreeIgnore(dirtreeIgnore bool) *ConfigBuilder {
+    b.config.DirtreeIgnore = dirtreeIgnore
+    return b
+}
+
+// WithSort sets the order of children and whether it is reversed
+func (b *ConfigBuilder) WithSort(sort string, descending bool) *ConfigBuilder {
+    b.config.Sort = sort
+    b.config.SortDescending = descending
+    return b
+}
+
+// WithDirsFirst sets whether directories are listed before files
+func (b *ConfigBuilder) WithDirsFirst(dirsFirst bool) *ConfigBuilder {
+    b.config.DirsFirst = dirsFirst
+    return b
+}
+
+// WithFilesFirst sets whether files are listed before directories
+func (b *ConfigBuilder) WithFilesFirst(filesFirst bool) *ConfigBuilder {
+    b.config.FilesFirst = filesFirst
+    return b
+}
+
+// WithErrorPolicy sets how unreadable paths are handled: abort, skip or record
+func (b *ConfigBuilder) WithErrorPolicy(policy string) *ConfigBuilder {
+    b.config.ErrorPolicy = policy
+    return b
+}
+
+// WithStream sets whether nodes are written while scanning, see dirtree.Stream
+func (b *ConfigBuilder) WithStream(stream bool) *ConfigBuilder {
+    b.config.Stream = stream
+    return b
+}
+
+// WithWatch sets whether the output is kept up to date as files change
+func (b *ConfigBuilder) WithWatch(watch bool) *ConfigBuilder {
+    b.config.Watch = watch
+    return b
+}
+
+// WithDebounce sets the quiet period before changes are applied in watch mode
+func (b *ConfigBuilder) WithDebounce(debounce time.Duration) *ConfigBuilder {
+    b.config.Debounce = debounce
+    return b
+}
+
+// WithEvents sets whether change events are written in watch mode
+func (b *ConfigBuilder) WithEvents(events bool) *ConfigBuilder {
+    b.config.Events = events
+    return b
+}
+
+// WithConcurrency sets the maximum number of directories scanned in parallel
+func (b *ConfigBuilder) WithConcurrency(concurrency int) *ConfigBuilder {
+    b.config.Concurrency = concurrency
+    return b
+}
+
+// WithTimeout sets the maximum scan duration
+func (b *ConfigBuilder) WithTimeout(timeout time.Duration) *ConfigBuilder {
+    b.config.Timeout = timeout
+    return b
+}
+
 // WithExcludePaths sets the path exclusion patterns
 func (b *ConfigBuilder) WithExcludePaths(excludePaths []string) *ConfigBuilder {
     b.config.ExcludePaths = excludePaths
@@ -127,6 +482,24 @@ func (b *ConfigBuilder) WithExcludeTypes(excludeTypes []string) *ConfigBuilder {
     return b
 }
 
+// WithExclude sets the glob, doublestar or regex exclusion patterns
+func (b *ConfigBuilder) WithExclude(patterns []string) *ConfigBuilder {
+    b.config.Exclude = patterns
+    return b
+}
+
+// WithInclude sets the glob, doublestar or regex inclusion patterns
+func (b *ConfigBuilder) WithInclude(patterns []string) *ConfigBuilder {
+    b.config.Include = patterns
+    return b
+}
+
+// WithFilter sets the find(1)-style filter expression
+func (b *ConfigBuilder) WithFilter(expr string) *ConfigBuilder {
+    b.config.Filter = expr
+    return b
+}
+
 // WithFormat sets the output format
 func (b *ConfigBuilder) WithFormat(format OutputFormat) *ConfigBuilder {
     b.config.Format.Type = format
@@ -145,6 +518,45 @@ func (b *ConfigBuilder) WithIndent(indent int) *ConfigBuilder {
     return b
 }
 
+// WithDiskUsage sets whether sizes are shown as disk usage instead of apparent size
+func (b *ConfigBuilder) WithDiskUsage(diskUsage bool) *ConfigBuilder {
+    b.config.Format.DiskUsage = diskUsage
+    return b
+}
+
+// WithStyle sets the style of the TXT format
+func (b *ConfigBuilder) WithStyle(style TXTStyle) *ConfigBuilder {
+    b.config.Format.Style = style
+    return b
+}
+
+// WithColor sets when TXT output is colored
+func (b *ConfigBuilder) WithColor(color ColorMode) *ConfigBuilder {
+    b.config.Format.Color = color
+    return b
+}
+
+// WithSizeFormat sets how sizes are presented
+func (b *ConfigBuilder) WithSizeFormat(format SizeFormat) *ConfigBuilder {
+    b.config.Format.SizeFormat = format
+    return b
+}
+
+// WithPercent sets whether each node's share of its parent's size is shown
+func (b *ConfigBuilder) WithPercent(percent bool) *ConfigBuilder {
+    b.config.Format.Percent = percent
+    return b
+}
+
+// WithOption sets a format specific option
+func (b *ConfigBuilder) WithOption(name, value string) *ConfigBuilder {
+    if b.config.Format.Options == nil {
+        b.config.Format.Options = make(map[string]string)
+    }
+    b.config.Format.Options[name] = value
+    return b
+}
+
 // WithExcludeNodeFields sets the node fields to exclude from output
 func (b *ConfigBuilder) WithExcludeNodeFields(fields []string) *ConfigBuilder {
     b.config.Format.ExcludeNodeFields = fields
@@ -163,6 +575,18 @@ func (b *ConfigBuilder) AddExcludeType(fileType string) *ConfigBuilder {
     return b
 }
 
+// AddExclude adds a pattern to the exclusion list
+func (b *ConfigBuilder) AddExclude(pattern string) *ConfigBuilder {
+    b.config.Exclude = append(b.config.Exclude, pattern)
+    return b
+}
+
+// AddInclude adds a pattern to the inclusion list
+func (b *ConfigBuilder) AddInclude(pattern string) *ConfigBuilder {
+    b.config.Include = append(b.config.Include, pattern)
+    return b
+}
+
 // AddExcludeNodeField adds a node field to the exclusion list
 func (b *ConfigBuilder) AddExcludeNodeField(field string) *ConfigBuilder {
     b.config.Format.ExcludeNodeFields = append(b.config.Format.ExcludeNodeFields, field)
@@ -173,21 +597,59 @@ func (b *ConfigBuilder) AddExcludeNodeField(field string) *ConfigBuilder {
 func (b *ConfigBuilder) Build() *Config {
     // Return a copy to avoid modifications after Build
     return &Config{
-        Path:         b.config.Path,
-        ExcludeTypes: append([]string{}, b.config.ExcludeTypes...),
-        ExcludePaths: append([]string{}, b.config.ExcludePaths...),
-        IncludeFiles: b.config.IncludeFiles,
-        MaxDepth:     b.config.MaxDepth,
-        FollowLinks:  b.config.FollowLinks,
+        Path:            b.config.Path,
+        ExcludeTypes:    append([]string{}, b.config.ExcludeTypes...),
+        ExcludePaths:    append([]string{}, b.config.ExcludePaths...),
+        Exclude:         append([]string{}, b.config.Exclude...),
+        Include:         append([]string{}, b.config.Include...),
+        Filter:          b.config.Filter,
+        IncludeFiles:    b.config.IncludeFiles,
+        MaxDepth:        b.config.MaxDepth,
+        FollowLinks:     b.config.FollowLinks,
+        ConfineLinks:    b.config.ConfineLinks,
+        CollectMetadata: b.config.CollectMetadata,
+        Hash:            b.config.Hash,
+        DedupHardLinks:  b.config.DedupHardLinks,
+        GitIgnore:       b.config.GitIgnore,
+        DirtreeIgnore:   b.config.DirtreeIgnore,
+        Sort:            b.config.Sort,
+        SortDescending:  b.config.SortDescending,
+        DirsFirst:       b.config.DirsFirst,
+        FilesFirst:      b.config.FilesFirst,
+        ErrorPolicy:     b.config.ErrorPolicy,
+        Concurrency:     b.config.Concurrency,
+        Timeout:         b.config.Timeout,
+        Stream:          b.config.Stream,
+        Watch:           b.config.Watch,
+        Debounce:        b.config.Debounce,
+        Events:          b.config.Events,
         Format: FormatCfg{
             Type:             b.config.Format.Type,
             OutputPath:       b.config.Format.OutputPath,
             Indent:           b.config.Format.Indent,
             ExcludeNodeFields: append([]string{}, b.config.Format.ExcludeNodeFields...),
+            DiskUsage:         b.config.Format.DiskUsage,
+            Style:             b.config.Format.Style,
+            Color:             b.config.Format.Color,
+            SizeFormat:        b.config.Format.SizeFormat,
+            Percent:           b.config.Format.Percent,
+            Options:           copyOptions(b.config.Format.Options),
         },
     }
 }
 
+// copyOptions returns a copy of format options, or nil if there are none
+func copyOptions(options map[string]string) map[string]string {
+    if len(options) == 0 {
+        return nil
+    }
+    result := make(map[string]string, len(options))
+    for name, value := range options {
+        result[name] = value
+    }
+    return result
+}
+
 // hasExtension checks if a path has the specified file extension
 func hasExtension(path, ext string) bool {
     if len(path) < len(ext) {
@@ -3,13 +3,25 @@ package configs
 import (
 	"flag"
 	"fmt"
+	"os"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// defaultExcludePath is the default of -ep: .git directories, without
+// matching names that merely contain ".git", such as "my.gitlab-notes"
+const defaultExcludePath = `(^|[/\\])\.git$`
+
 // ParseConfig parses configuration from command line flags and/or config file
 func ParseConfig() (*Config, error) {
+	return parseConfig(os.Args[1:])
+}
+
+// parseConfig is ParseConfig with the given command line arguments
+func parseConfig(args []string) (*Config, error) {
 	var configPath string
 	var path string
 	var outputFormat string
@@ -18,21 +30,76 @@ func ParseConfig() (*Config, error) {
 	var maxDepth int
 	var includeFiles bool
 	var followLinks bool
+	var confineLinks bool
+	var collectMetadata bool
+	var hash string
+	var dedupHardLinks bool
 	var excludeTypes string
+	var exclude string
+	var include string
+	var filter string
 	var excludeNodeFields string
+	var gitIgnore bool
+	var dirtreeIgnore bool
+	var sortBy string
+	var sortDescending bool
+	var dirsFirst bool
+	var filesFirst bool
+	var errorPolicy string
+	var concurrency int
+	var timeout time.Duration
+	var stream bool
+	var watch bool
+	var debounce time.Duration
+	var events bool
+	var diskUsage bool
+	var style string
+	var color string
+	var sizeFormat string
+	var percent bool
+	var formatOptions string
 	
 	// Command line flags
-	flag.StringVar(&configPath, "c", "", "Path to config file")
-	flag.StringVar(&path, "p", ".", "Target directory path")
-	flag.StringVar(&outputFormat, "f", "json", "Output format (json, yaml, xml, txt)")
-	flag.StringVar(&outputPath, "o", "output-dir", "Output file path")
-	flag.BoolVar(&includeFiles, "if", true, "Include files in output")
-	flag.BoolVar(&followLinks, "fl", false, "Follow symbolic links")
-	flag.StringVar(&excludePaths, "ep", ".git", "Exclude paths (regex patterns, comma separated)")
-	flag.StringVar(&excludeTypes, "et", "", "Exclude types (file extensions, comma separated)")
-	flag.IntVar(&maxDepth, "d", 1, "Maximum tree depth")
-	flag.StringVar(&excludeNodeFields, "enf", "size,is_hidden,type,path", "Exclude node fields from output (comma separated)")
-	flag.Parse()
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags.StringVar(&configPath, "c", "", "Path to config file")
+	flags.StringVar(&path, "p", ".", "Target directory path")
+	flags.StringVar(&outputFormat, "f", "json", fmt.Sprintf("Output format (%s)", joinFormats(Formats())))
+	flags.StringVar(&outputPath, "o", "output-dir", "Output file path")
+	flags.BoolVar(&includeFiles, "if", true, "Include files in output")
+	flags.BoolVar(&followLinks, "fl", false, "Follow symbolic links")
+	flags.BoolVar(&confineLinks, "cl", false, "Do not follow symbolic links that point outside the root")
+	flags.BoolVar(&collectMetadata, "meta", false, "Collect times, permissions, ownership and inode metadata")
+	flags.StringVar(&hash, "hash", "", "Digest file contents (sha256, sha1, md5)")
+	flags.BoolVar(&dedupHardLinks, "dedup", false, "Count hard-linked files only once in directory totals")
+	flags.StringVar(&excludePaths, "ep", defaultExcludePath, "Exclude paths (regex patterns, comma separated)")
+	flags.StringVar(&excludeTypes, "et", "", "Exclude types (file extensions, comma separated)")
+	flags.StringVar(&exclude, "x", "", "Exclude glob, doublestar or regex patterns relative to the root (comma separated, e.g. **/vendor/**,re:\\.tmp$)")
+	flags.StringVar(&include, "i", "", "Only include files matching glob, doublestar or regex patterns (comma separated)")
+	flags.StringVar(&filter, "filter", "", "find(1)-style filter expression, e.g. \"-type f -size +100M -mtime +90\"")
+	flags.IntVar(&maxDepth, "d", 1, "Maximum tree depth")
+	flags.BoolVar(&gitIgnore, "gi", false, "Skip paths ignored by .gitignore files and .git/info/exclude")
+	flags.BoolVar(&dirtreeIgnore, "dti", false, "Skip paths matched by .dirtreeignore files")
+	flags.StringVar(&sortBy, "sort", "name", "Sort children by name, natural (or version), ignore-case, size (disk usage with -du), mtime or extension")
+	flags.BoolVar(&sortDescending, "r", false, "Reverse the sort order")
+	flags.BoolVar(&dirsFirst, "dirsfirst", false, "List directories before files")
+	flags.BoolVar(&filesFirst, "filesfirst", false, "List files before directories")
+	flags.StringVar(&errorPolicy, "errors", "abort", "What to do with unreadable paths (abort, skip, record)")
+	flags.IntVar(&concurrency, "j", runtime.NumCPU(), "Maximum number of directories scanned in parallel")
+	flags.DurationVar(&timeout, "timeout", 0, "Stop scanning after this duration, e.g. 30s (0 for unlimited)")
+	flags.BoolVar(&stream, "stream", false, "Write nodes while scanning instead of building the whole tree in memory")
+	flags.BoolVar(&watch, "watch", false, "Keep rewriting the output as files change, until interrupted")
+	flags.DurationVar(&debounce, "debounce", 100*time.Millisecond, "Wait for this long without changes before updating in watch mode")
+	flags.BoolVar(&events, "events", false, "Write change events as NDJSON to stdout in watch mode")
+	flags.BoolVar(&diskUsage, "du", false, "Show disk usage instead of apparent file sizes")
+	flags.StringVar(&style, "style", "", "TXT style (emoji, unicode, ascii, plain), emoji by default and unicode in markdown trees")
+	flags.StringVar(&color, "color", "auto", "Color TXT output using LS_COLORS (auto, always, never)")
+	flags.StringVar(&sizeFormat, "size", "bytes", "Size format (bytes, si, iec, or a unit: B, kB, MB, GB, TB, KiB, MiB, GiB, TiB)")
+	flags.BoolVar(&percent, "percent", false, "Show each node's share of its parent's size")
+	flags.StringVar(&formatOptions, "fo", "", "Format specific options (comma separated key=value pairs)")
+	flags.StringVar(&excludeNodeFields, "enf", "size,is_hidden,type,path", "Exclude node fields from output (comma separated)")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
 
 	// Parse comma-separated strings into slices
 	excludePathsSlice := parseCommaSeparated(excludePaths)
@@ -40,17 +107,43 @@ func ParseConfig() (*Config, error) {
 	excludeNodeFieldsSlice := parseCommaSeparated(excludeNodeFields)
 
 	cfg := &Config{
-		Path:         path,
-		MaxDepth:     maxDepth,
-		ExcludePaths: excludePathsSlice,
-		ExcludeTypes: excludeTypesSlice,
-		IncludeFiles: includeFiles,
-		FollowLinks:  followLinks,
+		Path:            path,
+		MaxDepth:        maxDepth,
+		ExcludePaths:    excludePathsSlice,
+		ExcludeTypes:    excludeTypesSlice,
+		Exclude:         parseCommaSeparated(exclude),
+		Include:         parseCommaSeparated(include),
+		Filter:          filter,
+		IncludeFiles:    includeFiles,
+		FollowLinks:     followLinks,
+		ConfineLinks:    confineLinks,
+		CollectMetadata: collectMetadata,
+		Hash:            hash,
+		DedupHardLinks:  dedupHardLinks,
+		GitIgnore:       gitIgnore,
+		DirtreeIgnore:   dirtreeIgnore,
+		Sort:            sortBy,
+		SortDescending:  sortDescending,
+		DirsFirst:       dirsFirst,
+		FilesFirst:      filesFirst,
+		ErrorPolicy:     errorPolicy,
+		Concurrency:     concurrency,
+		Timeout:         timeout,
+		Stream:          stream,
+		Watch:           watch,
+		Debounce:        debounce,
+		Events:          events,
 		Format: FormatCfg{
-			Type:             OutputFormat(outputFormat),
-			OutputPath:       outputPath,
-			Indent:           2,
+			Type:              OutputFormat(outputFormat),
+			OutputPath:        outputPath,
+			Indent:            2,
 			ExcludeNodeFields: excludeNodeFieldsSlice,
+			DiskUsage:         diskUsage,
+			Style:             TXTStyle(style),
+			Color:             ColorMode(color),
+			SizeFormat:        SizeFormat(sizeFormat),
+			Percent:           percent,
+			Options:           parseOptions(formatOptions),
 		},
 	}
 
@@ -81,6 +174,30 @@ func parseCommaSeparated(input string) []string {
 	return result
 }
 
+// parseOptions parses comma-separated key=value pairs into a map
+func parseOptions(input string) map[string]string {
+	pairs := parseCommaSeparated(input)
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	options := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, _ := strings.Cut(pair, "=")
+		options[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return options
+}
+
+// joinFormats joins format names for help texts
+func joinFormats(names []OutputFormat) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = string(name)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // loadConfigFromFile loads configuration from a file using Viper
 func loadConfigFromFile(path string, cfg *Config) error {
 	viper.SetConfigFile(path)
@@ -1,7 +1,13 @@
 package configs
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/Maxim-Ba/dir-tree/tree"
 )
 
 // TestGetOutputPath tests the GetOutputPath method
@@ -218,6 +224,177 @@ func TestConfigValidate(t *testing.T) {
 			},
 			shouldError: false,
 		},
+		{
+			name: "Valid patterns",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Exclude:  []string{"**/vendor/**", "re:\\.tmp$"},
+				Include:  []string{"glob:*.go"},
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "Invalid exclude pattern",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Exclude:  []string{"re:("},
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Invalid include pattern",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Include:  []string{"[a-"},
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Valid filter",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Filter:   "-type f ( -size +100M -o -perm /002 )",
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "Invalid filter",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Filter:   "-size big",
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Valid error policy",
+			config: &Config{
+				Path:        "/valid/path",
+				MaxDepth:    1,
+				ErrorPolicy: "record",
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "Unsupported error policy",
+			config: &Config{
+				Path:        "/valid/path",
+				MaxDepth:    1,
+				ErrorPolicy: "ignore",
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Valid sort",
+			config: &Config{
+				Path:      "/valid/path",
+				MaxDepth:  1,
+				Sort:      "version",
+				DirsFirst: true,
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "Unsupported sort",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Sort:     "random",
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Dirs first and files first",
+			config: &Config{
+				Path:       "/valid/path",
+				MaxDepth:   1,
+				DirsFirst:  true,
+				FilesFirst: true,
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Unsupported txt style",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Format: FormatCfg{
+					Type:  TXT,
+					Style: "fancy",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Unsupported color mode",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Format: FormatCfg{
+					Type:  TXT,
+					Color: "sometimes",
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Valid fixed size unit",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Format: FormatCfg{
+					Type:       TXT,
+					SizeFormat: "MiB",
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "Unsupported size format",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Format: FormatCfg{
+					Type:       TXT,
+					SizeFormat: "mib",
+				},
+			},
+			shouldError: true,
+		},
 		{
 			name: "Valid TXT format",
 			config: &Config{
@@ -229,6 +406,107 @@ func TestConfigValidate(t *testing.T) {
 			},
 			shouldError: false,
 		},
+		{
+			name: "Valid streaming",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Stream:   true,
+				Sort:     "mtime",
+				Format: FormatCfg{
+					Type: NDJSON,
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "Streaming unsupported by format",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Stream:   true,
+				Format: FormatCfg{
+					Type: HTML,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Streaming sorted by size",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Stream:   true,
+				Sort:     "size",
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Unsupported hash algorithm",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Hash:     "crc32",
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Watching while streaming",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Stream:   true,
+				Watch:    true,
+				Format: FormatCfg{
+					Type: NDJSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Events without watching",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Events:   true,
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Negative debounce",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Watch:    true,
+				Debounce: -1,
+				Format: FormatCfg{
+					Type: JSON,
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Streaming with percentages",
+			config: &Config{
+				Path:     "/valid/path",
+				MaxDepth: 1,
+				Stream:   true,
+				Format: FormatCfg{
+					Type:    TXT,
+					Percent: true,
+				},
+			},
+			shouldError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -246,6 +524,141 @@ func TestConfigValidate(t *testing.T) {
 	}
 }
 
+// TestConfigBuildOptions tests conversion into tree build options
+func TestConfigBuildOptions(t *testing.T) {
+	cfg := New().
+		WithExcludeTypes([]string{".log"}).
+		WithExclude([]string{"**/vendor/**"}).
+		AddInclude("re:\\.go$").
+		Build()
+
+	opts, err := cfg.BuildOptions()
+	if err != nil {
+		t.Fatalf("BuildOptions() returned error: %v", err)
+	}
+	if !equalStringSlices(opts.ExcludeTypes, []string{".log"}) {
+		t.Errorf("ExcludeTypes = %v, want [.log]", opts.ExcludeTypes)
+	}
+	expectedExclude := []tree.Pattern{{Kind: tree.DoubleStarPattern, Expr: "**/vendor/**"}}
+	if !reflect.DeepEqual(opts.Exclude, expectedExclude) {
+		t.Errorf("Exclude = %v, want %v", opts.Exclude, expectedExclude)
+	}
+	expectedInclude := []tree.Pattern{{Kind: tree.RegexPattern, Expr: "\\.go$"}}
+	if !reflect.DeepEqual(opts.Include, expectedInclude) {
+		t.Errorf("Include = %v, want %v", opts.Include, expectedInclude)
+	}
+
+	if opts.SortBy != "" {
+		t.Errorf("SortBy = %q, want directory order", opts.SortBy)
+	}
+	if opts.Filter != nil {
+		t.Errorf("Filter = %v, want nil", opts.Filter)
+	}
+
+	cfg.Filter = "-mtime +90"
+	if opts, err = cfg.BuildOptions(); err != nil || opts.Filter == nil || opts.Filter.String() != "-mtime +90" {
+		t.Errorf("BuildOptions() Filter = %v, %v, want -mtime +90", opts.Filter, err)
+	}
+
+	cfg.Sort = "size"
+	if opts, err = cfg.BuildOptions(); err != nil || opts.SortBy != tree.SortBySize {
+		t.Errorf("BuildOptions() SortBy = %q, %v, want size", opts.SortBy, err)
+	}
+	cfg.Format.DiskUsage = true
+	if opts, err = cfg.BuildOptions(); err != nil || opts.SortBy != tree.SortByDiskUsage {
+		t.Errorf("BuildOptions() SortBy = %q, %v, want disk usage with -du", opts.SortBy, err)
+	}
+	cfg.Sort, cfg.Format.DiskUsage = "", false
+
+	cfg.Hash = "md5"
+	if opts, err = cfg.BuildOptions(); err != nil || opts.Hash != tree.MD5 {
+		t.Errorf("BuildOptions() Hash = %q, %v, want md5", opts.Hash, err)
+	}
+
+	cfg.Exclude = []string{"re:["}
+	if _, err := cfg.BuildOptions(); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+// TestParseDiffConfig tests the arguments of the diff command
+func TestParseDiffConfig(t *testing.T) {
+	cfg, err := ParseDiffConfig([]string{"-f", "patch", "-hash", "sha1", "-x", "*.log", "old.json", "new"}, io.Discard)
+	if err != nil {
+		t.Fatalf("ParseDiffConfig() returned error: %v", err)
+	}
+	if cfg.Old != "old.json" || cfg.New != "new" || cfg.Format != DiffPatch {
+		t.Errorf("ParseDiffConfig() = %+v", cfg)
+	}
+	if cfg.Scan.MaxDepth != -1 || cfg.Scan.Hash != "sha1" || !equalStringSlices(cfg.Scan.Exclude, []string{"*.log"}) {
+		t.Errorf("ParseDiffConfig() scan options = %+v", cfg.Scan)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"One tree", []string{"old"}},
+		{"Unknown flag", []string{"-unknown", "old", "new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDiffConfig(tt.args, io.Discard); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+
+	invalid := []*DiffConfig{
+		{Old: "a", New: "b", Format: "html", Scan: cfg.Scan},
+		{Old: "a", New: "b", Format: DiffTree, Color: "sometimes", Scan: cfg.Scan},
+		{Old: "a", New: "b", Format: DiffTree, Scan: Config{MaxDepth: -2, Format: FormatCfg{Type: JSON}}},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", c)
+		}
+	}
+}
+
+// TestParseConfigDefaultExclude tests that the default flags skip .git
+// directories but not names that merely contain ".git"
+func TestParseConfigDefaultExclude(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{".git", "my.gitlab-notes", "src"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", ".git"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := parseConfig([]string{"-p", dir, "-d", "-1"})
+	if err != nil {
+		t.Fatalf("parseConfig() returned error: %v", err)
+	}
+	opts, err := cfg.BuildOptions()
+	if err != nil {
+		t.Fatalf("BuildOptions() returned error: %v", err)
+	}
+	root, err := tree.BuildTree(opts)
+	if err != nil {
+		t.Fatalf("BuildTree() returned error: %v", err)
+	}
+
+	var names []string
+	for _, child := range root.Children {
+		names = append(names, child.Name)
+	}
+	if !equalStringSlices(names, []string{"my.gitlab-notes", "src"}) || len(root.Children[1].Children) != 0 {
+		t.Errorf("BuildTree() children = %v, want my.gitlab-notes and an empty src", names)
+	}
+}
+
 // TestConfigBuilder tests the ConfigBuilder methods
 func TestConfigBuilder(t *testing.T) {
 	tests := []struct {
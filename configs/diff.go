@@ -0,0 +1,127 @@
+package configs
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// DiffFormat represents the supported outputs of the diff command
+type DiffFormat string
+
+const (
+	DiffTree  DiffFormat = "tree"  // Unified tree of the changed nodes
+	DiffPatch DiffFormat = "patch" // RFC 6902 JSON Patch against the old tree's JSON
+	DiffJSON  DiffFormat = "json"  // JSON list of changes
+)
+
+// DiffConfig contains the configuration of the diff command
+type DiffConfig struct {
+	Old    string     // Directory or saved tree to compare from
+	New    string     // Directory or saved tree to compare to
+	Format DiffFormat // Output format: tree, patch or json
+	Color  ColorMode  // When to color the tree output
+	Scan   Config     // How live directories are scanned; Path is ignored
+}
+
+// Validate checks if the diff configuration is valid
+func (c *DiffConfig) Validate() error {
+	if c.Old == "" || c.New == "" {
+		return fmt.Errorf("two trees are needed to compare")
+	}
+
+	switch c.Format {
+	case DiffTree, DiffPatch, DiffJSON:
+		// valid formats
+	default:
+		return fmt.Errorf("unsupported diff format: %s", c.Format)
+	}
+
+	switch c.Color {
+	case "", ColorAuto, ColorAlways, ColorNever:
+		// valid modes
+	default:
+		return fmt.Errorf("unsupported color mode: %s", c.Color)
+	}
+
+	scan := c.Scan
+	scan.Path = c.Old
+	return scan.Validate()
+}
+
+// ParseDiffConfig parses the arguments of the diff command: flags
+// followed by the old and new tree
+func ParseDiffConfig(args []string, output io.Writer) (*DiffConfig, error) {
+	var format string
+	var color string
+	var maxDepth int
+	var includeFiles bool
+	var followLinks bool
+	var collectMetadata bool
+	var hash string
+	var excludePaths string
+	var excludeTypes string
+	var exclude string
+	var include string
+	var filter string
+	var gitIgnore bool
+	var dirtreeIgnore bool
+	var errorPolicy string
+	var concurrency int
+
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: dir-tree diff [flags] <old> <new>")
+		fmt.Fprintln(flags.Output(), "\nCompares two directories or saved trees. Exits with 0 if they are the same, 1 if they differ and 2 on errors.")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&format, "f", "tree", "Output format (tree, patch, json)")
+	flags.StringVar(&color, "color", "auto", "Color the tree output (auto, always, never)")
+	flags.IntVar(&maxDepth, "d", -1, "Maximum depth of scanned directories")
+	flags.BoolVar(&includeFiles, "if", true, "Include files")
+	flags.BoolVar(&followLinks, "fl", false, "Follow symbolic links")
+	flags.BoolVar(&collectMetadata, "meta", false, "Compare modification times and link targets of scanned directories")
+	flags.StringVar(&hash, "hash", "", "Compare file contents of scanned directories (sha256, sha1, md5)")
+	flags.StringVar(&excludePaths, "ep", defaultExcludePath, "Exclude paths (regex patterns, comma separated)")
+	flags.StringVar(&excludeTypes, "et", "", "Exclude types (file extensions, comma separated)")
+	flags.StringVar(&exclude, "x", "", "Exclude glob, doublestar or regex patterns relative to the root (comma separated)")
+	flags.StringVar(&include, "i", "", "Only include files matching glob, doublestar or regex patterns (comma separated)")
+	flags.StringVar(&filter, "filter", "", "find(1)-style filter expression")
+	flags.BoolVar(&gitIgnore, "gi", false, "Skip paths ignored by .gitignore files and .git/info/exclude")
+	flags.BoolVar(&dirtreeIgnore, "dti", false, "Skip paths matched by .dirtreeignore files")
+	flags.StringVar(&errorPolicy, "errors", "abort", "What to do with unreadable paths (abort, skip, record)")
+	flags.IntVar(&concurrency, "j", runtime.NumCPU(), "Maximum number of directories scanned in parallel")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return nil, fmt.Errorf("expected two trees to compare, got %d", flags.NArg())
+	}
+
+	return &DiffConfig{
+		Old:    flags.Arg(0),
+		New:    flags.Arg(1),
+		Format: DiffFormat(format),
+		Color:  ColorMode(color),
+		Scan: Config{
+			MaxDepth:        maxDepth,
+			ExcludePaths:    parseCommaSeparated(excludePaths),
+			ExcludeTypes:    parseCommaSeparated(excludeTypes),
+			Exclude:         parseCommaSeparated(exclude),
+			Include:         parseCommaSeparated(include),
+			Filter:          filter,
+			IncludeFiles:    includeFiles,
+			FollowLinks:     followLinks,
+			CollectMetadata: collectMetadata,
+			Hash:            hash,
+			GitIgnore:       gitIgnore,
+			DirtreeIgnore:   dirtreeIgnore,
+			ErrorPolicy:     errorPolicy,
+			Concurrency:     concurrency,
+			Format:          FormatCfg{Type: JSON},
+		},
+	}, nil
+}
@@ -0,0 +1,163 @@
+package configs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FormatDescriptor declares what Validate and GetOutputPath need to know
+// about an output format
+type FormatDescriptor struct {
+	Extension string         // File extension without the leading dot
+	Options   []FormatOption // Options the format accepts in FormatCfg.Options
+	Streaming bool           // Whether the format can be written while the tree is scanned
+}
+
+// FormatOption describes an option of an output format
+type FormatOption struct {
+	Name        string   // Key in FormatCfg.Options
+	Description string   // Help text
+	Default     string   // Value used when the option is not set
+	Values      []string // Accepted values, any value if empty
+}
+
+// formats holds the descriptors of the registered formats, starting with
+// the built-in ones. The formatter package provides their implementations.
+var (
+	formatsMu sync.RWMutex
+	formats   = map[OutputFormat]FormatDescriptor{
+		JSON:   {Extension: "json", Streaming: true},
+		YAML:   {Extension: "yaml", Streaming: true},
+		XML:    {Extension: "xml", Streaming: true},
+		TXT:    {Extension: "txt", Streaming: true},
+		NDJSON: {Extension: "ndjson", Streaming: true},
+		Markdown: {Extension: "md", Options: []FormatOption{
+			{Name: "variant", Description: "Layout: a box-drawn tree, a bullet list or a table", Default: string(MarkdownTree),
+				Values: []string{string(MarkdownTree), string(MarkdownList), string(MarkdownTable)}},
+			{Name: "links", Description: "Link list entries to their paths", Default: "false", Values: []string{"true", "false"}},
+			{Name: "link-base", Description: "Path prepended to link targets, e.g. the root's path from the document"},
+		}},
+		HTML: {Extension: "html", Options: []FormatOption{
+			{Name: "title", Description: "Page title, the root's name if empty"},
+			{Name: "expand", Description: "Number of levels expanded when the page opens", Default: "1"},
+		}},
+		SVG: {Extension: "svg", Options: []FormatOption{
+			{Name: "chart", Description: "Chart type", Default: string(SVGTreemap),
+				Values: []string{string(SVGTreemap), string(SVGSunburst)}},
+			{Name: "color", Description: "Color files by extension, or all nodes by depth", Default: "extension",
+				Values: []string{"extension", "depth"}},
+			{Name: "width", Description: "Image width in pixels", Default: "1200"},
+			{Name: "height", Description: "Image height in pixels", Default: "800"},
+		}},
+		DOT: {Extension: "dot", Options: []FormatOption{
+			{Name: "rank", Description: "Align nodes of the same depth", Default: "false", Values: []string{"true", "false"}},
+			{Name: "rankdir", Description: "Direction of the graph", Default: "LR", Values: []string{"TB", "LR", "BT", "RL"}},
+		}},
+	}
+)
+
+// RegisterFormat makes a format known to Validate and GetOutputPath.
+// Applications register formats through formatter.Register, which calls
+// it. Like formatter.Register, it panics if name is empty or a format,
+// built-in ones included, is already registered under name.
+func RegisterFormat(name OutputFormat, descriptor FormatDescriptor) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if name == "" {
+		panic("configs: RegisterFormat with empty name")
+	}
+	if _, dup := formats[name]; dup {
+		panic(fmt.Sprintf("configs: RegisterFormat called twice for format %s", name))
+	}
+	formats[name] = descriptor
+}
+
+// UnregisterFormat removes a format registered with RegisterFormat, so
+// that tests can register their own formats more than once. It panics
+// for the built-in formats.
+func UnregisterFormat(name OutputFormat) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	switch name {
+	case JSON, YAML, XML, TXT, NDJSON, Markdown, HTML, SVG, DOT:
+		panic(fmt.Sprintf("configs: UnregisterFormat called for built-in format %s", name))
+	}
+	delete(formats, name)
+}
+
+// LookupFormat returns the descriptor of a registered format
+func LookupFormat(name OutputFormat) (FormatDescriptor, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	descriptor, ok := formats[name]
+	return descriptor, ok
+}
+
+// Formats returns the names of all registered formats, sorted
+func Formats() []OutputFormat {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]OutputFormat, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// Option returns the value of a format option, or its declared default
+func (f *FormatCfg) Option(name string) string {
+	if value, ok := f.Options[name]; ok {
+		return value
+	}
+	descriptor, _ := LookupFormat(f.Type)
+	for _, option := range descriptor.Options {
+		if option.Name == name {
+			return option.Default
+		}
+	}
+	return ""
+}
+
+// validateFormat checks that the format is registered and its options
+// are declared and have accepted values
+func (f *FormatCfg) validateFormat() error {
+	descriptor, ok := LookupFormat(f.Type)
+	if !ok {
+		return fmt.Errorf("unsupported output format: %s", f.Type)
+	}
+
+	for name, value := range f.Options {
+		option, ok := findOption(descriptor.Options, name)
+		if !ok {
+			return fmt.Errorf("unsupported option %q for format %s", name, f.Type)
+		}
+		if len(option.Values) > 0 && !contains(option.Values, value) {
+			return fmt.Errorf("invalid value %q for option %s of format %s, expected one of: %s",
+				value, name, f.Type, strings.Join(option.Values, ", "))
+		}
+	}
+	return nil
+}
+
+// findOption returns the option with the given name
+func findOption(options []FormatOption, name string) (FormatOption, bool) {
+	for _, option := range options {
+		if option.Name == name {
+			return option, true
+		}
+	}
+	return FormatOption{}, false
+}
+
+// contains checks if a string slice contains a specific item
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package configs
+
+import "testing"
+
+// TestRegisterFormat tests the duplicate policy of RegisterFormat
+func TestRegisterFormat(t *testing.T) {
+	for _, name := range []OutputFormat{"", JSON} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic registering %q", name)
+				}
+			}()
+			RegisterFormat(name, FormatDescriptor{})
+		}()
+	}
+}
+
+// TestUnregisterFormat tests that formats can be registered again once
+// removed, unlike the built-in ones
+func TestUnregisterFormat(t *testing.T) {
+	const name OutputFormat = "unregister-test"
+	for i := 0; i < 2; i++ {
+		RegisterFormat(name, FormatDescriptor{Extension: "test"})
+		if _, ok := LookupFormat(name); !ok {
+			t.Fatalf("LookupFormat(%s) failed after registering", name)
+		}
+		UnregisterFormat(name)
+	}
+	if _, ok := LookupFormat(name); ok {
+		t.Errorf("LookupFormat(%s) succeeded after unregistering", name)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic unregistering a built-in format")
+		}
+	}()
+	UnregisterFormat(JSON)
+}
@@ -0,0 +1,165 @@
+package configs
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// UpdateConfig contains the configuration of the update command
+type UpdateConfig struct {
+	Files []string // Markdown documents whose sections are updated
+	Check bool     // Report stale sections instead of rewriting them
+}
+
+// Validate checks if the update configuration is valid
+func (c *UpdateConfig) Validate() error {
+	if len(c.Files) == 0 {
+		return fmt.Errorf("no documents to update")
+	}
+	return nil
+}
+
+// ParseUpdateConfig parses the arguments of the update command: flags
+// followed by the documents to update
+func ParseUpdateConfig(args []string, output io.Writer) (*UpdateConfig, error) {
+	var check bool
+
+	flags := flag.NewFlagSet("update", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: dir-tree update [flags] <file>...")
+		fmt.Fprintln(flags.Output(), "\nRegenerates the trees between <!-- dir-tree:start --> and <!-- dir-tree:end --> markers. With -check, exits with 1 if any tree is out of date.")
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&check, "check", false, "Only check that the trees are up to date, without writing")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return nil, fmt.Errorf("expected at least one document to update")
+	}
+
+	return &UpdateConfig{Files: flags.Args(), Check: check}, nil
+}
+
+// ParseSectionAttributes returns the configuration of a document section
+// from the attributes of its start marker, such as
+// `path=src depth=2 exclude="*_test.go,testdata" style=ascii`. Values are
+// bare words or quoted with double or single quotes. The tree is written
+// in the Markdown format, without sizes, types or paths unless fields is
+// given, and link-base defaults to the relative path of the section.
+func ParseSectionAttributes(attrs string) (*Config, error) {
+	b := New().
+		WithFormat(Markdown).
+		WithOutputPath("").
+		WithExclude([]string{".git"})
+
+	pairs, err := splitAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+	linkBase := false
+	for _, pair := range pairs {
+		name, value := pair[0], pair[1]
+		switch name {
+		case "path":
+			b.WithPath(value)
+		case "depth":
+			depth, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid depth %q: %w", value, err)
+			}
+			b.WithMaxDepth(depth)
+		case "exclude":
+			b.WithExclude(parseCommaSeparated(value))
+		case "include":
+			b.WithInclude(parseCommaSeparated(value))
+		case "filter":
+			b.WithFilter(value)
+		case "fields":
+			b.WithExcludeNodeFields(parseCommaSeparated(value))
+		case "files", "gitignore", "dirsfirst":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+			}
+			switch name {
+			case "files":
+				b.WithIncludeFiles(enabled)
+			case "gitignore":
+				b.WithGitIgnore(enabled)
+			default:
+				b.WithDirsFirst(enabled)
+			}
+		case "sort":
+			b.WithSort(value, false)
+		case "style":
+			b.WithStyle(TXTStyle(value))
+		case "variant", "links", "link-base":
+			b.WithOption(name, value)
+			linkBase = linkBase || name == "link-base"
+		default:
+			return nil, fmt.Errorf("unknown section attribute: %s", name)
+		}
+	}
+
+	switch style := b.config.Format.Style; style {
+	case "", EmojiStyle, UnicodeStyle, ASCIIStyle, PlainStyle:
+	default:
+		return nil, fmt.Errorf("invalid style %q in the section of %s, expected one of: %s, %s, %s, %s",
+			style, b.config.Path, EmojiStyle, UnicodeStyle, ASCIIStyle, PlainStyle)
+	}
+
+	// List links are relative to the section's root, so by default they
+	// start from its path to work from the document
+	section := path.Clean(filepath.ToSlash(b.config.Path))
+	if !linkBase && section != "." && !filepath.IsAbs(b.config.Path) {
+		b.WithOption("link-base", section)
+	}
+
+	cfg := b.Build()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// splitAttributes splits attributes into name and value pairs
+func splitAttributes(attrs string) ([][2]string, error) {
+	var pairs [][2]string
+	rest := strings.TrimSpace(attrs)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 || strings.IndexFunc(rest[:eq], unicode.IsSpace) != -1 {
+			return nil, fmt.Errorf("invalid section attribute: %s", strings.Fields(rest)[0])
+		}
+		name, value := rest[:eq], rest[eq+1:]
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			end := strings.IndexByte(value[1:], value[0])
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated value of section attribute %s", name)
+			}
+			rest = value[end+2:]
+			value = value[1 : end+1]
+			if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+				return nil, fmt.Errorf("expected space after section attribute %s", name)
+			}
+		} else if end := strings.IndexFunc(value, unicode.IsSpace); end != -1 {
+			value, rest = value[:end], value[end:]
+		} else {
+			rest = ""
+		}
+
+		pairs = append(pairs, [2]string{name, value})
+		rest = strings.TrimSpace(rest)
+	}
+	return pairs, nil
+}
@@ -0,0 +1,89 @@
+package configs
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestParseSectionAttributes tests reading section configurations from
+// start marker attributes
+func TestParseSectionAttributes(t *testing.T) {
+	cfg, err := ParseSectionAttributes(` path=src depth=-1 exclude="*_test.go, testdata" style='ascii' files=false variant=list`)
+	if err != nil {
+		t.Fatalf("ParseSectionAttributes() returned error: %v", err)
+	}
+	if cfg.Path != "src" || cfg.MaxDepth != -1 || cfg.IncludeFiles || cfg.Format.Style != ASCIIStyle {
+		t.Errorf("ParseSectionAttributes() = %+v", cfg)
+	}
+	if !equalStringSlices(cfg.Exclude, []string{"*_test.go", "testdata"}) {
+		t.Errorf("ParseSectionAttributes() exclude = %v", cfg.Exclude)
+	}
+	if cfg.Format.Type != Markdown || cfg.Format.OutputPath != "" || cfg.Format.Option("variant") != "list" {
+		t.Errorf("ParseSectionAttributes() format = %+v", cfg.Format)
+	}
+
+	if base := cfg.Format.Option("link-base"); base != "src" {
+		t.Errorf("ParseSectionAttributes() link-base = %q, want src", base)
+	}
+	for attrs, expected := range map[string]string{"path=./docs/api/": "docs/api", "path=src link-base=https://example.com": "https://example.com", "path=.": ""} {
+		cfg, err := ParseSectionAttributes(attrs)
+		if err != nil || cfg.Format.Option("link-base") != expected {
+			t.Errorf("ParseSectionAttributes(%q) link-base = %q, %v, want %q", attrs, cfg.Format.Option("link-base"), err, expected)
+		}
+	}
+
+	defaults, err := ParseSectionAttributes("")
+	if err != nil {
+		t.Fatalf("ParseSectionAttributes() returned error: %v", err)
+	}
+	if defaults.Path != "." || defaults.MaxDepth != 1 || !equalStringSlices(defaults.Exclude, []string{".git"}) {
+		t.Errorf("ParseSectionAttributes() defaults = %+v", defaults)
+	}
+
+	tests := []struct {
+		name  string
+		attrs string
+	}{
+		{"Unknown attribute", "colour=red"},
+		{"Missing value", "path"},
+		{"Unterminated quote", `path="src`},
+		{"Text after quote", `path="src"depth=2`},
+		{"Invalid depth", "depth=deep"},
+		{"Invalid boolean", "files=maybe"},
+		{"Invalid style", "style=fancy"},
+		{"Invalid variant", "variant=html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSectionAttributes(tt.attrs); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+
+	for _, style := range []TXTStyle{EmojiStyle, UnicodeStyle, ASCIIStyle, PlainStyle} {
+		cfg, err := ParseSectionAttributes("style=" + string(style))
+		if err != nil || cfg.Format.Style != style {
+			t.Errorf("ParseSectionAttributes(style=%s) = %+v, %v", style, cfg, err)
+		}
+	}
+	if _, err := ParseSectionAttributes("style=fancy path=docs"); err == nil || !strings.Contains(err.Error(), "docs") {
+		t.Errorf("ParseSectionAttributes() error = %v, want it to name the section", err)
+	}
+}
+
+// TestParseUpdateConfig tests parsing the arguments of the update command
+func TestParseUpdateConfig(t *testing.T) {
+	cfg, err := ParseUpdateConfig([]string{"--check", "README.md", "docs/layout.md"}, io.Discard)
+	if err != nil {
+		t.Fatalf("ParseUpdateConfig() returned error: %v", err)
+	}
+	if !cfg.Check || !equalStringSlices(cfg.Files, []string{"README.md", "docs/layout.md"}) {
+		t.Errorf("ParseUpdateConfig() = %+v", cfg)
+	}
+
+	if _, err := ParseUpdateConfig([]string{"-check"}, io.Discard); err == nil {
+		t.Error("Expected error without documents")
+	}
+}
@@ -2,8 +2,13 @@
 package dirtree
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Maxim-Ba/dir-tree/configs"
 	"github.com/Maxim-Ba/dir-tree/formatter"
@@ -12,33 +17,228 @@ import (
 
 // Generate creates a directory tree based on the provided configuration
 func Generate(cfg *configs.Config) ([]byte, error) {
-	root, err := tree.BuildTree(
-		tree.BuildOptions{Path: cfg.Path,
-		MaxDepth:     cfg.MaxDepth,
-		ExcludePaths: cfg.ExcludePaths,
-		ExcludeTypes: cfg.ExcludePaths,
-		IncludeFiles: cfg.IncludeFiles,
-		FollowLinks:  cfg.FollowLinks,
-	})
+	return GenerateContext(context.Background(), cfg)
+}
+
+// GenerateContext creates a directory tree like Generate, but stops scanning
+// once ctx is done. A cancelled scan still returns the formatted partial tree
+// together with the *tree.PartialError describing what was not read.
+func GenerateContext(ctx context.Context, cfg *configs.Config) ([]byte, error) {
+	opts, err := cfg.BuildOptions()
 	if err != nil {
 		return nil, err
 	}
-	return formatter.Format(root, &cfg.Format)
+
+	root, err := tree.BuildTreeContext(ctx, opts)
+	var partialErr *tree.PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		return nil, err
+	}
+
+	data, fmtErr := formatter.Format(root, &cfg.Format)
+	if fmtErr != nil {
+		return nil, fmtErr
+	}
+	return data, err
 }
 
-// GenerateToFile generates a directory tree and saves it to a file
+// GenerateToFile generates a directory tree and saves it to a file. A
+// partial tree is saved as well, and its *tree.PartialError returned.
 func GenerateToFile(cfg *configs.Config) error {
-	data, err := Generate(cfg)
+	return generateToFile(context.Background(), cfg)
+}
+
+// generateToFile implements GenerateToFile, scanning until ctx is done
+func generateToFile(ctx context.Context, cfg *configs.Config) error {
+	data, err := GenerateContext(ctx, cfg)
+	var partialErr *tree.PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		return err
+	}
+
+	outputPath := cfg.Format.GetOutputPath()
+	if outputPath == "" {
+		return fmt.Errorf("output path is required for file generation")
+	}
+
+	if writeErr := os.WriteFile(outputPath, data, 0644); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// Stream writes the directory tree to w while it is scanned, instead of
+// building it in memory first. The format must support streaming, see
+// formatter.NewEncoder and tree.WalkTree for the differences in output.
+func Stream(w io.Writer, cfg *configs.Config) error {
+	return StreamContext(context.Background(), w, cfg)
+}
+
+// StreamContext streams the directory tree like Stream, but stops scanning
+// once ctx is done. The output is still completed into a valid document,
+// and the *tree.PartialError describing what was not read is returned.
+func StreamContext(ctx context.Context, w io.Writer, cfg *configs.Config) error {
+	opts, err := cfg.BuildOptions()
+	if err != nil {
+		return err
+	}
+	enc, err := formatter.NewEncoder(w, &cfg.Format)
 	if err != nil {
 		return err
 	}
 
+	err = tree.WalkTree(ctx, opts, enc)
+	var partialErr *tree.PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		return err
+	}
+
+	if closeErr := enc.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
+}
+
+// StreamToFile streams the directory tree into the configured output file
+func StreamToFile(cfg *configs.Config) error {
 	outputPath := cfg.Format.GetOutputPath()
 	if outputPath == "" {
 		return fmt.Errorf("output path is required for file generation")
 	}
 
-	return os.WriteFile(outputPath, data, 0644)
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	err = Stream(file, cfg)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Load returns the tree at path. A directory is scanned with the options
+// of cfg, whose Path is ignored; any other file is parsed as a saved tree
+// in the format named by its extension. A scan that could not read every
+// path returns the partial tree together with a *tree.PartialError.
+func Load(ctx context.Context, path string, cfg *configs.Config) (*tree.Node, error) {
+	root, _, err := load(ctx, path, cfg)
+	return root, err
+}
+
+// load is Load, also returning the fields a saved tree was written
+// without, as tree.DiffOptions.Skip
+func load(ctx context.Context, path string, cfg *configs.Config) (*tree.Node, []string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		opts, err := cfg.BuildOptions()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Path = path
+		root, err := tree.BuildTreeContext(ctx, opts)
+		return root, nil, err
+	}
+
+	format, err := formatOf(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := formatter.Parse(data, format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return root, missingFields(root), nil
+}
+
+// missingFields returns "type" and "size" if a parsed tree has no node
+// with them. Empty fields are omitted from saved trees, so a tree that
+// only has empty files cannot be told apart from one saved without sizes.
+func missingFields(root *tree.Node) []string {
+	hasType, hasSize := false, false
+	var visit func(node *tree.Node)
+	visit = func(node *tree.Node) {
+		hasType = hasType || node.Type != ""
+		hasSize = hasSize || node.Size != 0
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	visit(root)
+
+	var missing []string
+	if !hasType {
+		missing = append(missing, "type")
+	}
+	if !hasSize {
+		missing = append(missing, "size")
+	}
+	return missing
+}
+
+// formatOf returns the registered format whose extension path has
+func formatOf(path string) (configs.OutputFormat, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext == "yml" {
+		ext = "yaml"
+	}
+	for _, name := range configs.Formats() {
+		if descriptor, _ := configs.LookupFormat(name); ext != "" && descriptor.Extension == ext {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot tell the format of %s from its extension", path)
+}
+
+// Diff loads two directories or saved trees with Load and compares them
+// with tree.Diff, returning both trees along with the changes. Types and
+// sizes are not compared when a saved tree was written without them.
+// Partial trees are compared as well, and the paths missing from either
+// are returned in a single *tree.PartialError.
+func Diff(ctx context.Context, oldPath, newPath string, cfg *configs.Config) (*tree.Node, *tree.Node, []tree.Change, error) {
+	var partialErr *tree.PartialError
+	old, oldMissing, oldErr := load(ctx, oldPath, cfg)
+	if oldErr != nil && !errors.As(oldErr, &partialErr) {
+		return nil, nil, nil, oldErr
+	}
+	current, missing, err := load(ctx, newPath, cfg)
+	if err != nil && !errors.As(err, &partialErr) {
+		return nil, nil, nil, err
+	}
+	opts := tree.DiffOptions{Skip: append(oldMissing, missing...)}
+	return old, current, tree.DiffWithOptions(old, current, opts), mergePartial(oldErr, err)
+}
+
+// mergePartial combines the *tree.PartialError of several scans into one,
+// or returns nil if every scan was complete
+func mergePartial(errs ...error) error {
+	var merged *tree.PartialError
+	for _, err := range errs {
+		var partialErr *tree.PartialError
+		if !errors.As(err, &partialErr) {
+			continue
+		}
+		if merged == nil {
+			merged = &tree.PartialError{}
+		}
+		if merged.Err == nil {
+			merged.Err = partialErr.Err
+		}
+		merged.Unscanned = append(merged.Unscanned, partialErr.Unscanned...)
+		merged.Failures = append(merged.Failures, partialErr.Failures...)
+	}
+	if merged == nil {
+		return nil
+	}
+	return merged
 }
 
 // GenerateJSON quickly generates a JSON directory tree (convenience method)
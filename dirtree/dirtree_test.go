@@ -0,0 +1,115 @@
+package dirtree
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// TestDiffPartial tests that Diff compares partial trees and reports the
+// paths missing from both
+func TestDiffPartial(t *testing.T) {
+	old, current := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(current, "new.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := configs.New().Build()
+
+	a, b, changes, err := Diff(context.Background(), old, current, cfg)
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("Diff() = %v, %v, want one change", changes, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a, b, changes, err = Diff(ctx, old, current, cfg)
+	var partialErr *tree.PartialError
+	if !errors.As(err, &partialErr) {
+		t.Fatalf("Diff() error = %v, want a *tree.PartialError", err)
+	}
+	if a == nil || b == nil || len(changes) != 0 {
+		t.Errorf("Diff() = %v, %v, %v, want both partial trees and no changes", a, b, changes)
+	}
+	if len(partialErr.Unscanned) != 2 || !errors.Is(err, context.Canceled) {
+		t.Errorf("PartialError = %+v, want both roots unscanned", partialErr)
+	}
+}
+
+// TestGenerateToFilePartial tests that a partial tree is saved along with
+// its *tree.PartialError
+func TestGenerateToFilePartial(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "tree")
+	cfg := configs.New().WithPath(dir).Build()
+	cfg.Format.OutputPath = output
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := generateToFile(ctx, cfg)
+	var partialErr *tree.PartialError
+	if !errors.As(err, &partialErr) {
+		t.Fatalf("generateToFile() error = %v, want a *tree.PartialError", err)
+	}
+	data, err := os.ReadFile(output + ".json")
+	if err != nil {
+		t.Fatalf("Partial tree was not saved: %v", err)
+	}
+	if !strings.Contains(string(data), filepath.Base(dir)) {
+		t.Errorf("Saved tree = %s, want the root", data)
+	}
+}
+
+// TestDiffExcludedFields tests that a snapshot written without types or
+// sizes matches the tree it was taken from
+func TestDiffExcludedFields(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("de"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		exclude []string
+	}{
+		{"defaults", configs.New().Build().Format.ExcludeNodeFields},
+		{"size", []string{"size"}},
+		{"type", []string{"type"}},
+		{"none", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := configs.New().WithPath(dir).Build()
+			cfg.MaxDepth = -1
+			cfg.Format.ExcludeNodeFields = tt.exclude
+			data, err := Generate(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+			if err := os.WriteFile(snapshot, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, changes, err := Diff(context.Background(), snapshot, dir, cfg)
+			if err != nil || len(changes) != 0 {
+				t.Errorf("Diff() = %+v, %v, want no changes", changes, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package dirtree
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/formatter"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+var (
+	// sectionStart matches the line starting a generated section, capturing
+	// its attributes
+	sectionStart = regexp.MustCompile(`^<!--\s*dir-tree:start\b(.*?)-->$`)
+	// sectionEnd matches the line ending a generated section
+	sectionEnd = regexp.MustCompile(`^<!--\s*dir-tree:end\s*-->$`)
+)
+
+// UpdateSections replaces the content of every section of a Markdown
+// document, between a <!-- dir-tree:start --> and a <!-- dir-tree:end -->
+// line, with a freshly generated tree. Each tree is configured by the
+// attributes of its start marker, see configs.ParseSectionAttributes,
+// with paths relative to dir. Markers inside fenced code blocks are left
+// alone, so documents can show them.
+func UpdateSections(ctx context.Context, doc []byte, dir string) ([]byte, error) {
+	var result bytes.Buffer
+	var fence string
+	var generated []byte
+	start := 0
+
+	lines := strings.SplitAfter(string(doc), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if generated != nil {
+			if sectionEnd.MatchString(trimmed) {
+				result.Write(generated)
+				result.WriteString(line)
+				generated = nil
+			}
+			continue
+		}
+
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case sectionEnd.MatchString(trimmed):
+			return nil, fmt.Errorf("line %d: dir-tree:end without dir-tree:start", i+1)
+		default:
+			if match := sectionStart.FindStringSubmatch(trimmed); match != nil {
+				data, err := generateSection(ctx, match[1], dir)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				if !strings.HasSuffix(line, "\n") {
+					line += "\n"
+				}
+				generated, start = data, i+1
+			}
+		}
+		result.WriteString(line)
+	}
+
+	if generated != nil {
+		return nil, fmt.Errorf("line %d: dir-tree:start without dir-tree:end", start)
+	}
+	return result.Bytes(), nil
+}
+
+// generateSection generates the tree of a section from the attributes of
+// its start marker. The tree is scanned at the path joined to dir, and
+// relocated so the output is the same wherever the document is updated
+// from.
+func generateSection(ctx context.Context, attrs, dir string) ([]byte, error) {
+	cfg, err := configs.ParseSectionAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+	section := cfg.Path
+	if !filepath.IsAbs(cfg.Path) {
+		cfg.Path = filepath.Join(dir, filepath.FromSlash(cfg.Path))
+	}
+
+	opts, err := cfg.BuildOptions()
+	if err != nil {
+		return nil, err
+	}
+	root, err := tree.BuildTreeContext(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error generating tree of %s: %w", section, err)
+	}
+	if err := relocate(root, cfg.Path, section); err != nil {
+		return nil, err
+	}
+	return formatter.Format(root, &cfg.Format)
+}
+
+// relocate rewrites a section's tree scanned at scanned: the root is
+// named after the directory even if the section's path is ".", paths are
+// made relative to the document like the section's path, and symbolic
+// link targets relative to the root of the section
+func relocate(root *tree.Node, scanned, section string) error {
+	abs, err := filepath.Abs(scanned)
+	if err != nil {
+		return err
+	}
+	root.Name = filepath.Base(abs)
+	// Targets are resolved like the root is, through every link
+	base := abs
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		base = resolved
+	}
+
+	var walk func(node *tree.Node)
+	walk = func(node *tree.Node) {
+		if rel, err := filepath.Rel(scanned, node.Path); err == nil {
+			node.Path = path.Join(filepath.ToSlash(section), filepath.ToSlash(rel))
+		}
+		if node.Target != "" {
+			target, err := filepath.Abs(node.Target)
+			if err == nil {
+				target, err = filepath.Rel(base, target)
+			}
+			if err == nil {
+				node.Target = filepath.ToSlash(target)
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return nil
+}
+
+// UpdateFile updates the sections of the Markdown document at path with
+// UpdateSections, relative to the directory of the document, and reports
+// whether any tree was out of date. With check set the document is only
+// compared, never written.
+func UpdateFile(ctx context.Context, path string, check bool) (bool, error) {
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	updated, err := UpdateSections(ctx, doc, filepath.Dir(path))
+	if err != nil {
+		return false, fmt.Errorf("error updating %s: %w", path, err)
+	}
+	if bytes.Equal(doc, updated) {
+		return false, nil
+	}
+	if check {
+		return true, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return true, err
+	}
+	return true, os.WriteFile(path, updated, info.Mode().Perm())
+}
@@ -0,0 +1,172 @@
+package dirtree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestUpdateSections tests regenerating the trees between section markers
+func TestUpdateSections(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"project/src/main.go", "project/src/util_test.go", "project/README.md"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name: "Replace stale tree",
+			doc: "# Layout\n" +
+				"<!-- dir-tree:start path=src exclude=\"*_test.go\" style=ascii -->\n" +
+				"old tree\n" +
+				"<!-- dir-tree:end -->\n" +
+				"More text\n",
+			expected: "# Layout\n" +
+				"<!-- dir-tree:start path=src exclude=\"*_test.go\" style=ascii -->\n" +
+				"```text\n" +
+				"src\n" +
+				"`-- main.go\n" +
+				"```\n" +
+				"<!-- dir-tree:end -->\n" +
+				"More text\n",
+		},
+		{
+			name: "Several sections",
+			doc: "<!--dir-tree:start variant=list files=false-->\n" +
+				"<!--dir-tree:end-->\n" +
+				"<!-- dir-tree:start path=src variant=list -->\n" +
+				"<!-- dir-tree:end -->",
+			expected: "<!--dir-tree:start variant=list files=false-->\n" +
+				"- project/\n" +
+				"  - src/\n" +
+				"<!--dir-tree:end-->\n" +
+				"<!-- dir-tree:start path=src variant=list -->\n" +
+				"- src/\n" +
+				"  - main.go\n" +
+				"  - util\\_test.go\n" +
+				"<!-- dir-tree:end -->",
+		},
+		{
+			name: "Links from the document",
+			doc: "<!-- dir-tree:start path=src variant=list links=true -->\n" +
+				"<!-- dir-tree:end -->\n",
+			expected: "<!-- dir-tree:start path=src variant=list links=true -->\n" +
+				"- [src/](src/)\n" +
+				"  - [main.go](src/main.go)\n" +
+				"  - [util\\_test.go](src/util_test.go)\n" +
+				"<!-- dir-tree:end -->\n",
+		},
+		{
+			name: "Markers in code blocks",
+			doc: "```markdown\n" +
+				"<!-- dir-tree:start -->\n" +
+				"<!-- dir-tree:end -->\n" +
+				"```\n",
+			expected: "```markdown\n" +
+				"<!-- dir-tree:start -->\n" +
+				"<!-- dir-tree:end -->\n" +
+				"```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := UpdateSections(context.Background(), []byte(tt.doc), filepath.Join(dir, "project"))
+			if err != nil {
+				t.Fatalf("UpdateSections returned error: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("UpdateSections() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+
+	invalid := []string{
+		"<!-- dir-tree:start -->\ntext\n",
+		"text\n<!-- dir-tree:end -->\n",
+		"<!-- dir-tree:start depth=deep -->\n<!-- dir-tree:end -->\n",
+		"<!-- dir-tree:start path=missing -->\n<!-- dir-tree:end -->\n",
+	}
+	for _, doc := range invalid {
+		if _, err := UpdateSections(context.Background(), []byte(doc), dir); err == nil {
+			t.Errorf("UpdateSections(%q) expected error", doc)
+		}
+	}
+}
+
+// TestUpdateFile tests checking and rewriting a document
+func TestUpdateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "README.md")
+	doc := "<!-- dir-tree:start -->\n<!-- dir-tree:end -->\n"
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		check, changed bool
+	}{
+		{check: true, changed: true},
+		{check: false, changed: true},
+		{check: true, changed: false},
+	} {
+		changed, err := UpdateFile(context.Background(), path, step.check)
+		if err != nil {
+			t.Fatalf("UpdateFile returned error: %v", err)
+		}
+		if changed != step.changed {
+			t.Errorf("UpdateFile(check=%v) = %v, want %v", step.check, changed, step.changed)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "README.md") {
+		t.Errorf("Updated document does not list itself:\n%s", data)
+	}
+}
+
+// TestUpdateSectionsRelocated tests that sections show link targets
+// relative to the section rather than machine-specific paths
+func TestUpdateSectionsRelocated(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "docs", "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "src", "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "docs", "src", "main.go"), filepath.Join(dir, "docs", "src", "link")); err != nil {
+		t.Skipf("Symbolic links not supported: %v", err)
+	}
+
+	doc := "<!-- dir-tree:start path=src fields=size,is_hidden,type -->\n<!-- dir-tree:end -->\n"
+	result, err := UpdateSections(context.Background(), []byte(doc), filepath.Join(dir, "docs"))
+	if err != nil {
+		t.Fatalf("UpdateSections returned error: %v", err)
+	}
+	expected := "<!-- dir-tree:start path=src fields=size,is_hidden,type -->\n" +
+		"```text\n" +
+		"src\n" +
+		"├── link -> main.go\n" +
+		"└── main.go\n" +
+		"```\n" +
+		"<!-- dir-tree:end -->\n"
+	if string(result) != expected {
+		t.Errorf("UpdateSections() =\n%s\nwant\n%s", result, expected)
+	}
+}
@@ -0,0 +1,171 @@
+package formatter
+
+import (
+	"os"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// defaultLSColors is used when LS_COLORS is not set, matching the
+// defaults of dircolors(1)
+const defaultLSColors = "rs=0:di=01;34:ln=01;36:mh=00:pi=40;33:so=01;35:do=01;35:bd=40;33;01:cd=40;33;01:" +
+	"or=40;31;01:mi=00:su=37;41:sg=30;43:ca=00:tw=30;42:ow=34;42:st=37;44:ex=01;32"
+
+// ResolveColor replaces the auto color mode of cfg with always or never.
+// Color is used when out is a terminal and NO_COLOR is not set, the way
+// ls --color=auto and tree -C behave.
+func ResolveColor(cfg *configs.FormatCfg, out *os.File) {
+	if cfg.Color != configs.ColorAuto {
+		return
+	}
+	cfg.Color = configs.ColorNever
+	if os.Getenv("NO_COLOR") != "" || out == nil {
+		return
+	}
+	if info, err := out.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		cfg.Color = configs.ColorAlways
+	}
+}
+
+// lsColors holds the SGR sequences of an LS_COLORS specification
+type lsColors struct {
+	kinds    map[string]string // Two-letter type keys such as "di" and "ln"
+	suffixes []lsSuffix        // "*.ext" style entries, later ones take precedence
+}
+
+// lsSuffix is an LS_COLORS entry matching names by their ending
+type lsSuffix struct {
+	suffix string // Lowercase suffix, e.g. ".tar"
+	code   string
+}
+
+// newColors returns the colors to use for cfg, or nil if output is not
+// colored. The auto mode must have been resolved with ResolveColor.
+func newColors(cfg *configs.FormatCfg) *lsColors {
+	if cfg.Color != configs.ColorAlways {
+		return nil
+	}
+	spec, ok := os.LookupEnv("LS_COLORS")
+	if !ok {
+		spec = defaultLSColors
+	}
+	return parseLSColors(spec)
+}
+
+// parseLSColors parses an LS_COLORS value such as "di=01;34:*.tar=01;31"
+func parseLSColors(spec string) *lsColors {
+	colors := &lsColors{kinds: map[string]string{}}
+	for _, entry := range strings.Split(spec, ":") {
+		key, code, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			continue
+		}
+		if strings.HasPrefix(key, "*") {
+			colors.suffixes = append(colors.suffixes, lsSuffix{suffix: strings.ToLower(key[1:]), code: code})
+			continue
+		}
+		colors.kinds[key] = code
+	}
+	return colors
+}
+
+// paint wraps text in the color of node, if it has one
+func (c *lsColors) paint(text string, node *tree.Node) string {
+	if c == nil {
+		return text
+	}
+	code := c.code(node)
+	if code == "" || code == "0" || code == "00" {
+		return text
+	}
+	reset := c.kinds["rs"]
+	if reset == "" {
+		reset = "0"
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[" + reset + "m"
+}
+
+// code returns the SGR sequence for node, following the precedence of
+// ls: special types and permission bits, then links, then extensions
+func (c *lsColors) code(node *tree.Node) string {
+	mode := node.Mode // Only set when metadata was collected
+	kind := byte('-')
+	if mode != "" {
+		kind = mode[0]
+	}
+
+	switch {
+	case node.Type == tree.Symlink:
+		if node.Target == "" {
+			return c.first("or", "ln")
+		}
+		if code := c.kinds["ln"]; code != "target" {
+			return code
+		}
+		// "ln=target" colors the link like the file it points to
+		return c.fileCode(node.Target, "")
+	case node.Type == tree.Directory:
+		other := len(mode) == 10 && mode[8] == 'w'
+		sticky := len(mode) == 10 && (mode[9] == 't' || mode[9] == 'T')
+		switch {
+		case sticky && other:
+			return c.first("tw", "di")
+		case other:
+			return c.first("ow", "di")
+		case sticky:
+			return c.first("st", "di")
+		}
+		return c.kinds["di"]
+	case kind == 'p':
+		return c.kinds["pi"]
+	case kind == 's':
+		return c.kinds["so"]
+	case kind == 'b':
+		return c.kinds["bd"]
+	case kind == 'c':
+		return c.kinds["cd"]
+	}
+	return c.fileCode(node.Name, mode)
+}
+
+// fileCode returns the color of a regular file from its permission
+// string, if known, and its name
+func (c *lsColors) fileCode(name, mode string) string {
+	if len(mode) == 10 {
+		switch {
+		case mode[3] == 's' || mode[3] == 'S':
+			if code := c.kinds["su"]; code != "" {
+				return code
+			}
+		case mode[6] == 's' || mode[6] == 'S':
+			if code := c.kinds["sg"]; code != "" {
+				return code
+			}
+		}
+		if strings.ContainsAny(mode[3:4]+mode[6:7]+mode[9:10], "xst") {
+			if code := c.kinds["ex"]; code != "" {
+				return code
+			}
+		}
+	}
+
+	lower := strings.ToLower(name)
+	for i := len(c.suffixes) - 1; i >= 0; i-- {
+		if strings.HasSuffix(lower, c.suffixes[i].suffix) {
+			return c.suffixes[i].code
+		}
+	}
+	return c.kinds["fi"]
+}
+
+// first returns the code of the first key that is set
+func (c *lsColors) first(keys ...string) string {
+	for _, key := range keys {
+		if code := c.kinds[key]; code != "" {
+			return code
+		}
+	}
+	return ""
+}
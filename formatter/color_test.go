@@ -0,0 +1,107 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// TestLSColorsCode tests color selection for different nodes
+func TestLSColorsCode(t *testing.T) {
+	colors := parseLSColors(defaultLSColors + ":fi=00:*.tar=01;31:*.TGZ=01;35:*.tar=31")
+
+	tests := []struct {
+		name     string
+		node     *tree.Node
+		expected string
+	}{
+		{"Directory", &tree.Node{Name: "src", Type: tree.Directory}, "01;34"},
+		{"Other-writable directory", &tree.Node{Name: "tmp", Type: tree.Directory, Mode: "drwxrwxrwx"}, "34;42"},
+		{"Sticky other-writable directory", &tree.Node{Name: "tmp", Type: tree.Directory, Mode: "drwxrwxrwt"}, "30;42"},
+		{"Symlink", &tree.Node{Name: "link", Type: tree.Symlink, Target: "/etc/hosts"}, "01;36"},
+		{"Broken symlink", &tree.Node{Name: "dangling", Type: tree.Symlink}, "40;31;01"},
+		{"Executable", &tree.Node{Name: "run.tar", Type: tree.File, Mode: "-rwxr-xr-x"}, "01;32"},
+		{"Setuid", &tree.Node{Name: "passwd", Type: tree.File, Mode: "-rwsr-xr-x"}, "37;41"},
+		{"Named pipe", &tree.Node{Name: "fifo", Type: tree.File, Mode: "prw-r--r--"}, "40;33"},
+		{"Later extension wins", &tree.Node{Name: "backup.tar", Type: tree.File}, "31"},
+		{"Extension ignores case", &tree.Node{Name: "backup.tgz", Type: tree.File}, "01;35"},
+		{"Plain file", &tree.Node{Name: "notes.txt", Type: tree.File, Mode: "-rw-r--r--"}, "00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := colors.code(tt.node); result != tt.expected {
+				t.Errorf("code() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+
+	target := parseLSColors("ln=target:*.go=33")
+	if result := target.code(&tree.Node{Name: "link", Type: tree.Symlink, Target: "/src/main.go"}); result != "33" {
+		t.Errorf("ln=target code() = %q, want 33", result)
+	}
+}
+
+// TestFormatTXTColor tests colored TXT output
+func TestFormatTXTColor(t *testing.T) {
+	t.Setenv("LS_COLORS", "di=01;34:*.go=33")
+	root := &tree.Node{
+		Name: "src",
+		Type: tree.Directory,
+		Children: []*tree.Node{
+			{Name: "main.go", Type: tree.File},
+			{Name: "README", Type: tree.File},
+		},
+	}
+
+	colored, err := Format(root, &configs.FormatCfg{Type: configs.TXT, Style: configs.PlainStyle, Color: configs.ColorAlways})
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	expected := "\x1b[01;34msrc\x1b[0m\n  \x1b[33mmain.go\x1b[0m\n  README\n\n0 directories, 2 files\n"
+	if string(colored) != expected {
+		t.Errorf("Format() = %q, want %q", colored, expected)
+	}
+
+	for _, mode := range []configs.ColorMode{"", configs.ColorAuto, configs.ColorNever} {
+		plain, err := Format(root, &configs.FormatCfg{Type: configs.TXT, Color: mode})
+		if err != nil {
+			t.Fatalf("Format returned error: %v", err)
+		}
+		if strings.Contains(string(plain), "\x1b[") {
+			t.Errorf("Expected no colors for mode %q, got %q", mode, plain)
+		}
+	}
+}
+
+// TestResolveColor tests resolving the auto color mode
+func TestResolveColor(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	cfg := &configs.FormatCfg{Color: configs.ColorAuto}
+	ResolveColor(cfg, file)
+	if cfg.Color != configs.ColorNever {
+		t.Errorf("Color = %q for a regular file, want never", cfg.Color)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	cfg = &configs.FormatCfg{Color: configs.ColorAuto}
+	ResolveColor(cfg, os.Stdout)
+	if cfg.Color != configs.ColorNever {
+		t.Errorf("Color = %q with NO_COLOR, want never", cfg.Color)
+	}
+
+	cfg = &configs.FormatCfg{Color: configs.ColorAlways}
+	ResolveColor(cfg, file)
+	if cfg.Color != configs.ColorAlways {
+		t.Errorf("Color = %q, want always to be kept", cfg.Color)
+	}
+}
@@ -0,0 +1,346 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// FormatDiff formats the changes tree.Diff found from tree a to tree b.
+// The color mode applies to the tree output and must have been resolved
+// with ResolveColor.
+func FormatDiff(a, b *tree.Node, changes []tree.Change, format configs.DiffFormat, color configs.ColorMode) ([]byte, error) {
+	switch format {
+	case configs.DiffTree:
+		return formatDiffTree(a, b, changes, color == configs.ColorAlways), nil
+	case configs.DiffPatch:
+		ops, err := diffPatch(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return marshalDiff(ops)
+	case configs.DiffJSON:
+		if changes == nil {
+			changes = []tree.Change{}
+		}
+		return marshalDiff(changes)
+	}
+	return nil, fmt.Errorf("unsupported diff format: %s", format)
+}
+
+// marshalDiff encodes a diff as indented JSON ending with a newline
+func marshalDiff(v interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error formatting diff: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// diffMarkers maps change kinds to their marker and SGR color
+var diffMarkers = map[tree.ChangeKind]struct{ marker, color string }{
+	tree.Added:       {"+", "32"},
+	tree.Removed:     {"-", "31"},
+	tree.Modified:    {"~", "33"},
+	tree.TypeChanged: {"!", "35"},
+}
+
+// diffEntry is a changed node, or a directory containing changes, in
+// the tree output
+type diffEntry struct {
+	name     string
+	change   *tree.Change
+	children []*diffEntry
+}
+
+// formatDiffTree formats the changed nodes and their directories as a
+// tree with unicode connectors, followed by a count of each change kind
+func formatDiffTree(a, b *tree.Node, changes []tree.Change, colored bool) []byte {
+	root := &diffEntry{name: a.Name}
+	if a.Name != b.Name {
+		root.name = a.Name + " → " + b.Name
+	}
+	counts := map[tree.ChangeKind]int{}
+	for i := range changes {
+		counts[changes[i].Kind]++
+		entry := root
+		if changes[i].Path != "." {
+			// Changes are ordered by path, so a directory seen before is
+			// the last child added to its parent
+			for _, name := range strings.Split(changes[i].Path, "/") {
+				last := len(entry.children) - 1
+				if last < 0 || entry.children[last].name != name {
+					entry.children = append(entry.children, &diffEntry{name: name})
+					last++
+				}
+				entry = entry.children[last]
+			}
+		}
+		entry.change = &changes[i]
+	}
+
+	var result strings.Builder
+	connectors := txtStyles[configs.UnicodeStyle]
+	var write func(entry *diffEntry, prefix string)
+	write = func(entry *diffEntry, prefix string) {
+		for i, child := range entry.children {
+			line, childPrefix := prefix+connectors.branch, prefix+connectors.vertical
+			if i == len(entry.children)-1 {
+				line, childPrefix = prefix+connectors.last, prefix+connectors.space
+			}
+			result.WriteString(line + diffLine(child, colored) + "\n")
+			write(child, childPrefix)
+		}
+	}
+	result.WriteString(diffLine(root, colored) + "\n")
+	write(root, "")
+
+	if len(changes) == 0 {
+		result.WriteString("\nNo differences\n")
+	} else {
+		result.WriteString(fmt.Sprintf("\n%d added, %d removed, %d modified, %d type changed\n",
+			counts[tree.Added], counts[tree.Removed], counts[tree.Modified], counts[tree.TypeChanged]))
+	}
+	return []byte(result.String())
+}
+
+// diffLine formats an entry of the tree output, without indentation
+func diffLine(entry *diffEntry, colored bool) string {
+	c := entry.change
+	if c == nil {
+		return entry.name
+	}
+
+	line := diffMarkers[c.Kind].marker + " " + entry.name
+	switch c.Kind {
+	case tree.Added:
+		line += diffSummary(c.New)
+	case tree.Removed:
+		line += diffSummary(c.Old)
+	case tree.TypeChanged:
+		line += fmt.Sprintf(" (%s → %s)", c.Old.Type, c.New.Type)
+	case tree.Modified:
+		details := make([]string, 0, len(c.Fields))
+		for _, field := range c.Fields {
+			switch field {
+			case "size":
+				details = append(details, fmt.Sprintf("size %d → %d bytes", c.Old.Size, c.New.Size))
+			case "mod_time":
+				details = append(details, fmt.Sprintf("mtime %s → %s",
+					c.Old.ModTime.Format("2006-01-02 15:04:05"), c.New.ModTime.Format("2006-01-02 15:04:05")))
+			case "hash":
+				details = append(details, "content")
+			case "link_target":
+				details = append(details, fmt.Sprintf("-> %s → %s", c.Old.LinkTarget, c.New.LinkTarget))
+			}
+		}
+		line += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	if !colored {
+		return line
+	}
+	return "\x1b[" + diffMarkers[c.Kind].color + "m" + line + "\x1b[0m"
+}
+
+// diffSummary describes an added or removed node
+func diffSummary(node *tree.Node) string {
+	switch {
+	case node.Type == tree.Directory:
+		return fmt.Sprintf("/ (%s, %s)", plural(node.DirCount, "directory", "directories"), plural(node.FileCount, "file", "files"))
+	case node.Type == tree.File:
+		return " (" + FormatSize(node.Size, configs.BytesSize) + ")"
+	case node.LinkTarget != "":
+		return " -> " + node.LinkTarget
+	}
+	return ""
+}
+
+// patchOp is an RFC 6902 JSON Patch operation
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// pointerEscaper escapes a key as a JSON Pointer reference token
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// diffPatch returns a JSON Patch turning the JSON of tree a into the JSON
+// of tree b. Children are matched by name, so nodes found in both trees
+// keep their place and only their fields that differ, totals and paths
+// included, are patched.
+func diffPatch(a, b *tree.Node) ([]patchOp, error) {
+	old, err := jsonValue(a)
+	if err != nil {
+		return nil, fmt.Errorf("error formatting diff: %w", err)
+	}
+	current, err := jsonValue(b)
+	if err != nil {
+		return nil, fmt.Errorf("error formatting diff: %w", err)
+	}
+
+	ops := []patchOp{}
+	if err := patchValue(&ops, "", old, current); err != nil {
+		return nil, fmt.Errorf("error formatting diff: %w", err)
+	}
+	return ops, nil
+}
+
+// jsonValue returns the JSON of v decoded into maps, slices and scalars.
+// Numbers are kept as json.Number so large sizes are written back exactly.
+func jsonValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	err = decoder.Decode(&value)
+	return value, err
+}
+
+// patchValue appends the operations turning the JSON value old at pointer
+// into current. Objects are patched key by key and lists of nodes child by
+// child; any other change replaces the value.
+func patchValue(ops *[]patchOp, pointer string, old, current interface{}) error {
+	if reflect.DeepEqual(old, current) {
+		return nil
+	}
+	switch old := old.(type) {
+	case map[string]interface{}:
+		if current, ok := current.(map[string]interface{}); ok {
+			return patchObject(ops, pointer, old, current)
+		}
+	case []interface{}:
+		if current, ok := current.([]interface{}); ok {
+			return patchChildren(ops, pointer, old, current)
+		}
+	}
+	return addOp(ops, "replace", pointer, current)
+}
+
+// patchObject appends the operations turning the object old into current.
+// Empty fields are omitted from the JSON, so they are added or removed
+// rather than replaced.
+func patchObject(ops *[]patchOp, pointer string, old, current map[string]interface{}) error {
+	for _, key := range sortedKeys(old) {
+		if _, ok := current[key]; !ok {
+			*ops = append(*ops, patchOp{Op: "remove", Path: pointer + "/" + pointerEscaper.Replace(key)})
+		}
+	}
+	for _, key := range sortedKeys(current) {
+		field := pointer + "/" + pointerEscaper.Replace(key)
+		value, ok := old[key]
+		if !ok {
+			if err := addOp(ops, "add", field, current[key]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := patchValue(ops, field, value, current[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchChildren appends the operations turning the list of nodes old into
+// current, matching nodes by name. Nodes are removed from the end, so the
+// indices of earlier ones stay valid, and added at their final index; the
+// nodes found in both lists are then patched in place. Lists that are not
+// made of uniquely named nodes, or whose common nodes were reordered, are
+// replaced as a whole.
+func patchChildren(ops *[]patchOp, pointer string, old, current []interface{}) error {
+	oldIndex, currentIndex := nameIndex(old), nameIndex(current)
+	if oldIndex == nil || currentIndex == nil {
+		return addOp(ops, "replace", pointer, current)
+	}
+
+	var kept, order []string
+	for _, node := range old {
+		if _, ok := currentIndex[nodeName(node)]; ok {
+			kept = append(kept, nodeName(node))
+		}
+	}
+	for _, node := range current {
+		if _, ok := oldIndex[nodeName(node)]; ok {
+			order = append(order, nodeName(node))
+		}
+	}
+	if !slices.Equal(kept, order) {
+		return addOp(ops, "replace", pointer, current)
+	}
+
+	for i := len(old) - 1; i >= 0; i-- {
+		if _, ok := currentIndex[nodeName(old[i])]; !ok {
+			*ops = append(*ops, patchOp{Op: "remove", Path: pointer + "/" + strconv.Itoa(i)})
+		}
+	}
+	for i, node := range current {
+		if _, ok := oldIndex[nodeName(node)]; !ok {
+			if err := addOp(ops, "add", pointer+"/"+strconv.Itoa(i), node); err != nil {
+				return err
+			}
+		}
+	}
+	for i, node := range current {
+		if j, ok := oldIndex[nodeName(node)]; ok {
+			if err := patchValue(ops, pointer+"/"+strconv.Itoa(i), old[j], node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nameIndex indexes a list of nodes by name. It returns nil if the list
+// holds anything but uniquely named nodes.
+func nameIndex(nodes []interface{}) map[string]int {
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		name := nodeName(node)
+		if _, dup := index[name]; name == "" || dup {
+			return nil
+		}
+		index[name] = i
+	}
+	return index
+}
+
+// nodeName returns the name of a node decoded from JSON, or "" if the
+// value is not a named node
+func nodeName(node interface{}) string {
+	object, _ := node.(map[string]interface{})
+	name, _ := object["name"].(string)
+	return name
+}
+
+// sortedKeys returns the keys of an object in order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addOp appends an operation setting the value at pointer
+func addOp(ops *[]patchOp, op, pointer string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	*ops = append(*ops, patchOp{Op: op, Path: pointer, Value: data})
+	return nil
+}
@@ -0,0 +1,224 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// diffTrees returns an old and a new tree with every kind of change
+func diffTrees() (*tree.Node, *tree.Node) {
+	old := &tree.Node{Name: "old", Type: tree.Directory, Children: []*tree.Node{
+		{Name: "docs", Type: tree.Directory, Children: []*tree.Node{
+			{Name: "guide.md", Type: tree.File, Size: 10},
+			{Name: "old.md", Type: tree.File, Size: 1},
+		}},
+		{Name: "empty", Type: tree.Directory},
+		{Name: "main.go", Type: tree.File, Size: 5, Hash: "md5:aa"},
+		{Name: "swap", Type: tree.File, Size: 1},
+	}}
+	current := &tree.Node{Name: "new", Type: tree.Directory, Children: []*tree.Node{
+		{Name: "docs", Type: tree.Directory, Children: []*tree.Node{
+			{Name: "api.md", Type: tree.File, Size: 3},
+			{Name: "guide.md", Type: tree.File, Size: 12},
+		}},
+		{Name: "empty", Type: tree.Directory, Children: []*tree.Node{
+			{Name: "new.txt", Type: tree.File},
+		}},
+		{Name: "main.go", Type: tree.File, Size: 5, Hash: "md5:bb"},
+		{Name: "swap", Type: tree.Directory},
+	}}
+	return old, current
+}
+
+// TestFormatDiffTree tests the unified tree output
+func TestFormatDiffTree(t *testing.T) {
+	old, current := diffTrees()
+	data, err := FormatDiff(old, current, tree.Diff(old, current), configs.DiffTree, configs.ColorNever)
+	if err != nil {
+		t.Fatalf("FormatDiff returned error: %v", err)
+	}
+	expected := "old → new\n" +
+		"├── docs\n" +
+		"│   ├── + api.md (3 bytes)\n" +
+		"│   ├── ~ guide.md (size 10 → 12 bytes)\n" +
+		"│   └── - old.md (1 bytes)\n" +
+		"├── empty\n" +
+		"│   └── + new.txt (0 bytes)\n" +
+		"├── ~ main.go (content)\n" +
+		"└── ! swap (file → directory)\n" +
+		"\n2 added, 1 removed, 2 modified, 1 type changed\n"
+	if string(data) != expected {
+		t.Errorf("FormatDiff() =\n%s\nwant\n%s", data, expected)
+	}
+
+	data, err = FormatDiff(old, current, tree.Diff(old, current), configs.DiffTree, configs.ColorAlways)
+	if err != nil || !strings.Contains(string(data), "\x1b[32m+ api.md (3 bytes)\x1b[0m") {
+		t.Errorf("Colored FormatDiff() =\n%q, %v", data, err)
+	}
+
+	data, _ = FormatDiff(old, old, nil, configs.DiffTree, configs.ColorNever)
+	if string(data) != "old\n\nNo differences\n" {
+		t.Errorf("FormatDiff() without changes = %q", data)
+	}
+}
+
+// TestFormatDiffPatch tests that the JSON Patch turns the JSON of the old
+// tree into the JSON of the new one
+func TestFormatDiffPatch(t *testing.T) {
+	old, current := diffTrees()
+	old.Size, old.FileCount, current.Size, current.FileCount = 17, 4, 20, 5
+	old.Path, current.Path = "/tmp/old", "/tmp/new"
+	renamed := &tree.Node{Name: "b", Children: []*tree.Node{{Name: "y"}, {Name: "x"}}}
+
+	tests := []struct {
+		name     string
+		old, new *tree.Node
+	}{
+		{"Changes", old, current},
+		{"Reverse", current, old},
+		{"Reordered", &tree.Node{Name: "a", Children: []*tree.Node{{Name: "x"}, {Name: "y"}}}, renamed},
+		{"Same", old, old},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := FormatDiff(tt.old, tt.new, tree.Diff(tt.old, tt.new), configs.DiffPatch, configs.ColorNever)
+			if err != nil {
+				t.Fatalf("FormatDiff returned error: %v", err)
+			}
+			var ops []patchOp
+			if err := json.Unmarshal(data, &ops); err != nil {
+				t.Fatalf("Invalid patch: %v\n%s", err, data)
+			}
+
+			doc, _ := jsonValue(tt.old)
+			for _, op := range ops {
+				if doc, err = applyOp(doc, op); err != nil {
+					t.Fatalf("Applying %s %s: %v\n%s", op.Op, op.Path, err, data)
+				}
+			}
+			want, _ := jsonValue(tt.new)
+			if !reflect.DeepEqual(doc, want) {
+				got, _ := json.Marshal(doc)
+				t.Errorf("Patched JSON =\n%s\nwant the new tree; patch:\n%s", got, data)
+			}
+		})
+	}
+
+	data, _ := FormatDiff(old, old, nil, configs.DiffPatch, configs.ColorNever)
+	if string(data) != "[]\n" {
+		t.Errorf("FormatDiff() without changes = %q", data)
+	}
+
+	data, _ = FormatDiff(old, current, nil, configs.DiffPatch, configs.ColorNever)
+	var ops []patchOp
+	json.Unmarshal(data, &ops)
+	for _, op := range ops {
+		if op.Path == "/children" || op.Path == "" {
+			t.Errorf("Patch replaces %q instead of patching the children", op.Path)
+		}
+	}
+}
+
+// applyOp applies a JSON Patch operation to a document decoded with
+// jsonValue and returns the new document
+func applyOp(doc interface{}, op patchOp) (interface{}, error) {
+	var value interface{}
+	if op.Op != "remove" {
+		decoder := json.NewDecoder(bytes.NewReader(op.Value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	if op.Path == "" {
+		return value, nil
+	}
+	tokens := strings.Split(op.Path, "/")[1:]
+	for i := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[i])
+	}
+	return applyAt(doc, tokens, op.Op, value)
+}
+
+// applyAt applies an operation at the location tokens points to below
+// node and returns the new node
+func applyAt(node interface{}, tokens []string, op string, value interface{}) (interface{}, error) {
+	token, rest := tokens[0], tokens[1:]
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[token]
+		if !ok && (len(rest) > 0 || op != "add") {
+			return nil, fmt.Errorf("missing key %s", token)
+		}
+		if len(rest) > 0 {
+			child, err := applyAt(child, rest, op, value)
+			container[token] = child
+			return container, err
+		}
+		if op == "remove" {
+			delete(container, token)
+		} else {
+			container[token] = value
+		}
+		return container, nil
+	case []interface{}:
+		limit := len(container)
+		if len(rest) == 0 && op == "add" {
+			limit++
+		}
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= limit {
+			return nil, fmt.Errorf("invalid index %s", token)
+		}
+		if len(rest) > 0 {
+			container[i], err = applyAt(container[i], rest, op, value)
+			return container, err
+		}
+		switch op {
+		case "add":
+			return slices.Insert(container, i, value), nil
+		case "remove":
+			return slices.Delete(container, i, i+1), nil
+		}
+		container[i] = value
+		return container, nil
+	}
+	return nil, fmt.Errorf("no object or array at %s", token)
+}
+
+// TestFormatDiffJSON tests the change list output
+func TestFormatDiffJSON(t *testing.T) {
+	old, current := diffTrees()
+	changes := tree.Diff(old, current)
+	data, err := FormatDiff(old, current, changes, configs.DiffJSON, configs.ColorNever)
+	if err != nil {
+		t.Fatalf("FormatDiff returned error: %v", err)
+	}
+
+	var result []tree.Change
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Invalid change list: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(result, changes) {
+		t.Errorf("Change list =\n%s\nwant %+v", data, changes)
+	}
+
+	data, _ = FormatDiff(old, old, nil, configs.DiffJSON, configs.ColorNever)
+	if string(data) != "[]\n" {
+		t.Errorf("FormatDiff() without changes = %q", data)
+	}
+
+	if _, err := FormatDiff(old, current, changes, "html", configs.ColorNever); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
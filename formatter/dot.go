@@ -0,0 +1,149 @@
+package formatter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// dotStyles maps node types to their Graphviz shape and fill color
+var dotStyles = map[tree.FileType][2]string{
+	tree.Directory: {"folder", "#dbeafe"},
+	tree.File:      {"note", "#f3f4f6"},
+	tree.Symlink:   {"cds", "#fef3c7"},
+}
+
+// dotQuoter escapes the characters that end or break a quoted DOT string
+var dotQuoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+// formatDOT formats the tree as a Graphviz digraph with an edge from every
+// directory to each of its children. Shapes and colors tell node types
+// apart, and symbolic links get a dashed edge to their target, which is
+// drawn outside the tree if it was not scanned. With the "rank" option,
+// nodes of the same depth are aligned.
+func formatDOT(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+	name := node.Name
+	if contains(cfg.ExcludeNodeFields, "name") {
+		name = "tree"
+	}
+	var result strings.Builder
+	fmt.Fprintf(&result, "digraph %s {\n", dotQuote(name))
+	fmt.Fprintf(&result, "  rankdir=%s;\n", cfg.Option("rankdir"))
+	result.WriteString("  node [fontname=\"Helvetica\", fontsize=10, style=filled];\n")
+	result.WriteString("  edge [color=\"#9ca3af\"];\n\n")
+
+	// Number the nodes first, so links can point to nodes written later
+	ids := map[*tree.Node]string{}
+	byPath := map[string]*tree.Node{}
+	var ranks [][]string
+	var number func(node *tree.Node, depth int)
+	number = func(node *tree.Node, depth int) {
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[node] = id
+		if node.Path != "" {
+			byPath[dotPathKey(node.Path)] = node
+		}
+		if depth == len(ranks) {
+			ranks = append(ranks, nil)
+		}
+		ranks[depth] = append(ranks[depth], id)
+		if contains(cfg.ExcludeNodeFields, "children") {
+			return
+		}
+		for _, child := range node.Children {
+			number(child, depth+1)
+		}
+	}
+	number(node, 0)
+
+	var edges, links strings.Builder
+	outside := map[string]string{}
+	var write func(node, parent *tree.Node)
+	write = func(node, parent *tree.Node) {
+		id := ids[node]
+		style, ok := dotStyles[node.Type]
+		if !ok {
+			style = dotStyles[tree.File]
+		}
+		fmt.Fprintf(&result, "  %s [label=%s, shape=%s, fillcolor=%q", id, dotQuote(dotLabel(node, parent, cfg)), style[0], style[1])
+		if node.Error != "" {
+			result.WriteString(", color=\"#cf222e\"")
+		}
+		result.WriteString("];\n")
+
+		if parent != nil {
+			fmt.Fprintf(&edges, "  %s -> %s;\n", ids[parent], id)
+		}
+		if node.Target != "" && !contains(cfg.ExcludeNodeFields, "target") {
+			target, scanned := ids[byPath[dotPathKey(node.Target)]]
+			if !scanned {
+				// Each target outside the tree is drawn once
+				if target = outside[node.Target]; target == "" {
+					target = fmt.Sprintf("t%d", len(outside))
+					outside[node.Target] = target
+					fmt.Fprintf(&result, "  %s [label=%s, shape=box, style=dashed];\n", target, dotQuote(node.Target))
+				}
+			}
+			fmt.Fprintf(&links, "  %s -> %s [style=dashed, color=\"#d97706\", constraint=false];\n", id, target)
+		}
+
+		if contains(cfg.ExcludeNodeFields, "children") {
+			return
+		}
+		for _, child := range node.Children {
+			write(child, node)
+		}
+	}
+	write(node, nil)
+
+	if edges.Len() > 0 || links.Len() > 0 {
+		result.WriteString("\n")
+		result.WriteString(edges.String())
+		result.WriteString(links.String())
+	}
+	if cfg.Option("rank") == "true" {
+		result.WriteString("\n")
+		for _, rank := range ranks {
+			fmt.Fprintf(&result, "  { rank=same; %s; }\n", strings.Join(rank, "; "))
+		}
+	}
+	result.WriteString("}\n")
+	return []byte(result.String()), nil
+}
+
+// dotLabel returns the label of a node: its name, then its size and any
+// error on lines of their own, as far as they are not excluded
+func dotLabel(node, parent *tree.Node, cfg *configs.FormatCfg) string {
+	var lines []string
+	if !contains(cfg.ExcludeNodeFields, "name") {
+		name := node.Name
+		if node.Type == tree.Directory {
+			name += "/"
+		}
+		lines = append(lines, name)
+	}
+	if size, field := nodeSize(node, cfg); !contains(cfg.ExcludeNodeFields, field) && size > 0 {
+		lines = append(lines, strings.Trim(formatSizeDetail(node, parent, cfg), "()"))
+	}
+	if !contains(cfg.ExcludeNodeFields, "error") && node.Error != "" {
+		lines = append(lines, node.Error)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dotPathKey returns the cleaned absolute form of a path, so that link
+// targets find their node however the root was written, e.g. "./x"
+func dotPathKey(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Clean(p)
+}
+
+// dotQuote returns s as a quoted DOT string
+func dotQuote(s string) string {
+	return `"` + dotQuoter.Replace(s) + `"`
+}
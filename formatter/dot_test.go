@@ -0,0 +1,137 @@
+package formatter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// TestFormatDOT tests the nodes, edges and ranks of the DOT format
+func TestFormatDOT(t *testing.T) {
+	root := &tree.Node{
+		Name: "project",
+		Path: "/project",
+		Type: tree.Directory,
+		Size: 10,
+		Children: []*tree.Node{
+			{Name: "docs", Path: "/project/docs", Type: tree.Directory, Error: "permission denied"},
+			{Name: `say "hi".txt`, Path: "/project/say \"hi\".txt", Type: tree.File, Size: 10},
+			{Name: "latest", Path: "/project/latest", Type: tree.Symlink, Target: "/project/docs"},
+			{Name: "hosts", Path: "/project/hosts", Type: tree.Symlink, Target: "/etc/hosts"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		excludeFields []string
+		expected      string
+	}{
+		{
+			name:    "All fields with ranks",
+			options: map[string]string{"rank": "true", "rankdir": "TB"},
+			expected: `digraph "project" {
+  rankdir=TB;
+  node [fontname="Helvetica", fontsize=10, style=filled];
+  edge [color="#9ca3af"];
+
+  n0 [label="project/\n10 bytes", shape=folder, fillcolor="#dbeafe"];
+  n1 [label="docs/\npermission denied", shape=folder, fillcolor="#dbeafe", color="#cf222e"];
+  n2 [label="say \"hi\".txt\n10 bytes", shape=note, fillcolor="#f3f4f6"];
+  n3 [label="latest", shape=cds, fillcolor="#fef3c7"];
+  n4 [label="hosts", shape=cds, fillcolor="#fef3c7"];
+  t0 [label="/etc/hosts", shape=box, style=dashed];
+
+  n0 -> n1;
+  n0 -> n2;
+  n0 -> n3;
+  n0 -> n4;
+  n3 -> n1 [style=dashed, color="#d97706", constraint=false];
+  n4 -> t0 [style=dashed, color="#d97706", constraint=false];
+
+  { rank=same; n0; }
+  { rank=same; n1; n2; n3; n4; }
+}
+`,
+		},
+		{
+			name:          "Excluded fields",
+			excludeFields: []string{"size", "target", "error"},
+			expected: `digraph "project" {
+  rankdir=LR;
+  node [fontname="Helvetica", fontsize=10, style=filled];
+  edge [color="#9ca3af"];
+
+  n0 [label="project/", shape=folder, fillcolor="#dbeafe"];
+  n1 [label="docs/", shape=folder, fillcolor="#dbeafe", color="#cf222e"];
+  n2 [label="say \"hi\".txt", shape=note, fillcolor="#f3f4f6"];
+  n3 [label="latest", shape=cds, fillcolor="#fef3c7"];
+  n4 [label="hosts", shape=cds, fillcolor="#fef3c7"];
+
+  n0 -> n1;
+  n0 -> n2;
+  n0 -> n3;
+  n0 -> n4;
+}
+`,
+		},
+		{
+			name:          "Without children",
+			options:       map[string]string{"rank": "true"},
+			excludeFields: []string{"name", "children"},
+			expected: `digraph "tree" {
+  rankdir=LR;
+  node [fontname="Helvetica", fontsize=10, style=filled];
+  edge [color="#9ca3af"];
+
+  n0 [label="10 bytes", shape=folder, fillcolor="#dbeafe"];
+
+  { rank=same; n0; }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &configs.FormatCfg{Type: configs.DOT, ExcludeNodeFields: tt.excludeFields, Options: tt.options}
+			result, err := Format(root, cfg)
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestFormatDOTRelativeRoot tests that link targets find their node when
+// the root path is not clean or the target is absolute
+func TestFormatDOTRelativeRoot(t *testing.T) {
+	abs, err := filepath.Abs("x/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := &tree.Node{Name: "x", Path: "./x", Type: tree.Directory, Children: []*tree.Node{
+		{Name: "docs", Path: "x/docs", Type: tree.Directory},
+		{Name: "home", Path: "x/home", Type: tree.Symlink, Target: "x"},
+		{Name: "latest", Path: "x/latest", Type: tree.Symlink, Target: abs},
+	}}
+
+	result, err := Format(root, &configs.FormatCfg{Type: configs.DOT})
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	for _, edge := range []string{"n2 -> n0 [style=dashed", "n3 -> n1 [style=dashed"} {
+		if !strings.Contains(string(result), edge) {
+			t.Errorf("Expected %q in\n%s", edge, result)
+		}
+	}
+	if strings.Contains(string(result), "t0") {
+		t.Errorf("Targets in the tree were drawn outside it:\n%s", result)
+	}
+}
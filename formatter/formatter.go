@@ -5,36 +5,59 @@ import (
 	"encoding/xml"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Maxim-Ba/dir-tree/configs"
 	"github.com/Maxim-Ba/dir-tree/tree"
 	"gopkg.in/yaml.v2"
 )
 
-// Format converts a tree node to the specified output format
+// Format converts a tree node to the specified output format, using the
+// formatter registered for cfg.Type
 func Format(tree *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
-	switch cfg.Type {
-	case configs.JSON:
-		return formatJSON(tree, cfg)
-	case configs.YAML:
-		return formatYAML(tree, cfg)
-	case configs.XML:
-		return formatXML(tree, cfg)
-	case configs.TXT:
-		return formatTXT(tree, 0, cfg), nil
-	default:
+	impl, ok := Lookup(cfg.Type)
+	if !ok {
 		return nil, fmt.Errorf("unsupported format: %s", cfg.Type)
 	}
+	return impl.Format(tree, cfg)
 }
 
 // filteredNode represents a node with filtered fields for output
 type filteredNode struct {
-	Name     string          `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
-	Path     string          `json:"path,omitempty" yaml:"path,omitempty" xml:"path,omitempty"`
-	Type     tree.FileType   `json:"type,omitempty" yaml:"type,omitempty" xml:"type,omitempty"`
-	Size     int64           `json:"size,omitempty" yaml:"size,omitempty" xml:"size,omitempty"`
+	Name      string        `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
+	Path      string        `json:"path,omitempty" yaml:"path,omitempty" xml:"path,omitempty"`
+	Type      tree.FileType `json:"type,omitempty" yaml:"type,omitempty" xml:"type,omitempty"`
+	Size      int64         `json:"size,omitempty" yaml:"size,omitempty" xml:"size,omitempty"`
+	DiskUsage int64         `json:"disk_usage,omitempty" yaml:"disk_usage,omitempty" xml:"disk_usage,omitempty"`
+
+	SizeHuman      string   `json:"size_human,omitempty" yaml:"size_human,omitempty" xml:"size_human,omitempty"`
+	DiskUsageHuman string   `json:"disk_usage_human,omitempty" yaml:"disk_usage_human,omitempty" xml:"disk_usage_human,omitempty"`
+	Percent        *float64 `json:"percent,omitempty" yaml:"percent,omitempty" xml:"percent,omitempty"` // Share of the parent's size
+
 	Children []*filteredNode `json:"children,omitempty" yaml:"children,omitempty" xml:"children>node,omitempty"`
 	IsHidden bool            `json:"is_hidden,omitempty" yaml:"is_hidden,omitempty" xml:"is_hidden,omitempty"`
+	Target   string          `json:"target,omitempty" yaml:"target,omitempty" xml:"target,omitempty"`
+	Cycle    bool            `json:"cycle,omitempty" yaml:"cycle,omitempty" xml:"cycle,omitempty"`
+	Error    string          `json:"error,omitempty" yaml:"error,omitempty" xml:"error,omitempty"`
+	Hash     string          `json:"hash,omitempty" yaml:"hash,omitempty" xml:"hash,omitempty"`
+
+	FileCount int `json:"file_count,omitempty" yaml:"file_count,omitempty" xml:"file_count,omitempty"`
+	DirCount  int `json:"dir_count,omitempty" yaml:"dir_count,omitempty" xml:"dir_count,omitempty"`
+	MaxDepth  int `json:"max_depth,omitempty" yaml:"max_depth,omitempty" xml:"max_depth,omitempty"`
+
+	ModTime    *time.Time `json:"mod_time,omitempty" yaml:"mod_time,omitempty" xml:"mod_time,omitempty"`
+	AccessTime *time.Time `json:"access_time,omitempty" yaml:"access_time,omitempty" xml:"access_time,omitempty"`
+	ChangeTime *time.Time `json:"change_time,omitempty" yaml:"change_time,omitempty" xml:"change_time,omitempty"`
+	Mode       string     `json:"mode,omitempty" yaml:"mode,omitempty" xml:"mode,omitempty"`
+	Perm       string     `json:"perm,omitempty" yaml:"perm,omitempty" xml:"perm,omitempty"`
+	UID        *uint32    `json:"uid,omitempty" yaml:"uid,omitempty" xml:"uid,omitempty"`
+	GID        *uint32    `json:"gid,omitempty" yaml:"gid,omitempty" xml:"gid,omitempty"`
+	Owner      string     `json:"owner,omitempty" yaml:"owner,omitempty" xml:"owner,omitempty"`
+	Group      string     `json:"group,omitempty" yaml:"group,omitempty" xml:"group,omitempty"`
+	Inode      uint64     `json:"inode,omitempty" yaml:"inode,omitempty" xml:"inode,omitempty"`
+	Device     uint64     `json:"device,omitempty" yaml:"device,omitempty" xml:"device,omitempty"`
+	Links      uint64     `json:"links,omitempty" yaml:"links,omitempty" xml:"links,omitempty"`
+	LinkTarget string     `json:"link_target,omitempty" yaml:"link_target,omitempty" xml:"link_target,omitempty"`
 }
 
 // createFilteredNode creates a filtered node with excluded fields removed
@@ -58,9 +81,72 @@ func createFilteredNode(node *tree.Node, excludeFields []string) *filteredNode {
 	if !contains(excludeFields, "size") {
 		filtered.Size = node.Size
 	}
+	if !contains(excludeFields, "disk_usage") {
+		filtered.DiskUsage = node.DiskUsage
+	}
 	if !contains(excludeFields, "is_hidden") {
 		filtered.IsHidden = node.IsHidden
 	}
+	if !contains(excludeFields, "target") {
+		filtered.Target = node.Target
+	}
+	if !contains(excludeFields, "cycle") {
+		filtered.Cycle = node.Cycle
+	}
+	if !contains(excludeFields, "error") {
+		filtered.Error = node.Error
+	}
+	if !contains(excludeFields, "hash") {
+		filtered.Hash = node.Hash
+	}
+	if !contains(excludeFields, "file_count") {
+		filtered.FileCount = node.FileCount
+	}
+	if !contains(excludeFields, "dir_count") {
+		filtered.DirCount = node.DirCount
+	}
+	if !contains(excludeFields, "max_depth") {
+		filtered.MaxDepth = node.MaxDepth
+	}
+	if !contains(excludeFields, "mod_time") {
+		filtered.ModTime = node.ModTime
+	}
+	if !contains(excludeFields, "access_time") {
+		filtered.AccessTime = node.AccessTime
+	}
+	if !contains(excludeFields, "change_time") {
+		filtered.ChangeTime = node.ChangeTime
+	}
+	if !contains(excludeFields, "mode") {
+		filtered.Mode = node.Mode
+	}
+	if !contains(excludeFields, "perm") {
+		filtered.Perm = node.Perm
+	}
+	if !contains(excludeFields, "uid") {
+		filtered.UID = node.UID
+	}
+	if !contains(excludeFields, "gid") {
+		filtered.GID = node.GID
+	}
+	if !contains(excludeFields, "owner") {
+		filtered.Owner = node.Owner
+	}
+	if !contains(excludeFields, "group") {
+		filtered.Group = node.Group
+	}
+	if !contains(excludeFields, "inode") {
+		filtered.Inode = node.Inode
+	}
+	if !contains(excludeFields, "device") {
+		filtered.Device = node.Device
+	}
+	if !contains(excludeFields, "links") {
+		filtered.Links = node.Links
+	}
+	if !contains(excludeFields, "link_target") {
+		filtered.LinkTarget = node.LinkTarget
+	}
 
 	// Recursively process children (if children field is not excluded)
 	if !contains(excludeFields, "children") && node.Children != nil {
@@ -76,6 +162,20 @@ func createFilteredNode(node *tree.Node, excludeFields []string) *filteredNode {
 	return filtered
 }
 
+// outputData returns the value the structured formats encode: the node
+// itself, or a filtered copy when fields are excluded or sizes decorated
+func outputData(node *tree.Node, cfg *configs.FormatCfg) interface{} {
+	if len(cfg.ExcludeNodeFields) == 0 && !humanSizes(cfg) && !cfg.Percent {
+		return node
+	}
+
+	filtered := createFilteredNode(node, cfg.ExcludeNodeFields)
+	if filtered != nil {
+		addSizeDetails(filtered, node, nil, cfg)
+	}
+	return filtered
+}
+
 // contains checks if a string slice contains a specific item
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -88,12 +188,7 @@ func contains(slice []string, item string) bool {
 
 // formatJSON formats the tree as JSON
 func formatJSON(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
-	var data interface{} = node
-
-	// Apply field filtering if needed
-	if len(cfg.ExcludeNodeFields) > 0 {
-		data = createFilteredNode(node, cfg.ExcludeNodeFields)
-	}
+	data := outputData(node, cfg)
 
 	if cfg.Indent > 0 {
 		return json.MarshalIndent(data, "", strings.Repeat(" ", cfg.Indent))
@@ -103,53 +198,121 @@ func formatJSON(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
 
 // formatYAML formats the tree as YAML
 func formatYAML(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
-	var data interface{} = node
-
-	// Apply field filtering if needed
-	if len(cfg.ExcludeNodeFields) > 0 {
-		data = createFilteredNode(node, cfg.ExcludeNodeFields)
-	}
+	data := outputData(node, cfg)
 
 	return yaml.Marshal(data)
 }
 
 // formatXML formats the tree as XML
 func formatXML(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
-	var data interface{} = node
-
-	// Apply field filtering if needed
-	if len(cfg.ExcludeNodeFields) > 0 {
-		data = createFilteredNode(node, cfg.ExcludeNodeFields)
-	}
+	data := outputData(node, cfg)
 
 	return xml.MarshalIndent(data, "", "  ")
 }
 
-// formatTXT formats the tree as plain text with visual indicators
-func formatTXT(node *tree.Node, level int, cfg *configs.FormatCfg) []byte {
+// txtConnectors holds the line prefixes of a box-drawing TXT style
+type txtConnectors struct {
+	branch, last, vertical, space string
+}
+
+// txtStyles maps connector-based TXT styles to their connectors
+var txtStyles = map[configs.TXTStyle]txtConnectors{
+	configs.UnicodeStyle: {branch: "├── ", last: "└── ", vertical: "│   ", space: "    "},
+	configs.ASCIIStyle:   {branch: "|-- ", last: "`-- ", vertical: "|   ", space: "    "},
+}
+
+// formatTXT formats the tree as plain text in the style selected by cfg,
+// followed by a summary of the directories and files listed
+func formatTXT(node *tree.Node, cfg *configs.FormatCfg) []byte {
 	var result strings.Builder
-	indent := strings.Repeat("  ", level)
+	dirs, files := writeTXTTree(&result, node, cfg, newColors(cfg))
+	result.WriteString(fmt.Sprintf("\n%s, %s\n", plural(dirs, "directory", "directories"), plural(files, "file", "files")))
+	return []byte(result.String())
+}
+
+// writeTXTTree writes the lines of the tree in the style selected by cfg
+// to result, and returns the number of directories and files listed
+func writeTXTTree(result *strings.Builder, node *tree.Node, cfg *configs.FormatCfg, colors *lsColors) (dirs, files int) {
+	var write func(node *tree.Node, level int, prefix string)
+	write = func(node *tree.Node, level int, prefix string) {
+		if level > 0 {
+			if node.Type == tree.Directory {
+				dirs++
+			} else {
+				files++
+			}
+		}
+		if contains(cfg.ExcludeNodeFields, "children") {
+			return
+		}
+
+		connectors, boxed := txtStyles[cfg.Style]
+		for i, child := range node.Children {
+			line, childPrefix := strings.Repeat("  ", level+1), ""
+			if boxed {
+				line, childPrefix = prefix+connectors.branch, prefix+connectors.vertical
+				if i == len(node.Children)-1 {
+					line, childPrefix = prefix+connectors.last, prefix+connectors.space
+				}
+			}
+			result.WriteString(line + txtLine(child, node, cfg, colors) + "\n")
+			write(child, level+1, childPrefix)
+		}
+	}
+
+	result.WriteString(txtLine(node, nil, cfg, colors) + "\n")
+	write(node, 0, "")
+	return dirs, files
+}
+
+// plural formats a count with the singular or plural noun
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, pluralForm)
+}
 
+// txtLine formats the text of a single node, without indentation.
+// parent is nil for the root. The name is colored when colors is not nil.
+func txtLine(node, parent *tree.Node, cfg *configs.FormatCfg, colors *lsColors) string {
 	// Build line parts based on included fields
 	parts := []string{}
 
 	// Add prefix for type indication
-	prefix := "📁 " // directory
-	if node.Type == tree.File {
-		prefix = "📄 " // file
-	} else if node.Type == tree.Symlink {
-		prefix = "🔗 " // symlink
+	if cfg.Style == configs.EmojiStyle || cfg.Style == "" {
+		prefix := "📁 " // directory
+		if node.Type == tree.File {
+			prefix = "📄 " // file
+		} else if node.Type == tree.Symlink {
+			prefix = "🔗 " // symlink
+		}
+		parts = append(parts, prefix)
+	}
+
+	// Add permissions (if not excluded and metadata was collected)
+	if !contains(cfg.ExcludeNodeFields, "mode") && node.Mode != "" {
+		parts = append(parts, node.Mode)
+	}
+
+	// Add owner and group (if not excluded and metadata was collected)
+	if owner := formatOwner(node, cfg.ExcludeNodeFields); owner != "" {
+		parts = append(parts, owner)
+	}
+
+	// Add modification time (if not excluded and metadata was collected)
+	if !contains(cfg.ExcludeNodeFields, "mod_time") && node.ModTime != nil {
+		parts = append(parts, node.ModTime.Format("2006-01-02 15:04"))
 	}
-	parts = append(parts, prefix)
 
 	// Add name (if not excluded)
 	if !contains(cfg.ExcludeNodeFields, "name") {
-		parts = append(parts, node.Name)
+		parts = append(parts, colors.paint(node.Name, node))
 	}
 
-	// Add size (if not excluded and if file with size > 0)
-	if !contains(cfg.ExcludeNodeFields, "size") && node.Type == tree.File && node.Size > 0 {
-		parts = append(parts, fmt.Sprintf("(%d bytes)", node.Size))
+	// Add size (if not excluded and if file or directory total with size > 0)
+	if size, field := nodeSize(node, cfg); !contains(cfg.ExcludeNodeFields, field) && node.Type != tree.Symlink && size > 0 {
+		parts = append(parts, formatSizeDetail(node, parent, cfg))
 	}
 
 	// Add hidden status (if not excluded and file is hidden)
@@ -157,14 +320,50 @@ func formatTXT(node *tree.Node, level int, cfg *configs.FormatCfg) []byte {
 		parts = append(parts, "[hidden]")
 	}
 
-	result.WriteString(fmt.Sprintf("%s%s\n", indent, strings.Join(parts, " ")))
+	// Add link target (if not excluded and node is a resolved link)
+	if !contains(cfg.ExcludeNodeFields, "target") && node.Target != "" {
+		parts = append(parts, "-> "+node.Target)
+	}
+
+	// Add cycle marker (if not excluded and link loops back to an ancestor)
+	if !contains(cfg.ExcludeNodeFields, "cycle") && node.Cycle {
+		parts = append(parts, "[cycle]")
+	}
 
-	// Recursively process children (if children field is not excluded)
-	if !contains(cfg.ExcludeNodeFields, "children") {
-		for _, child := range node.Children {
-			result.Write(formatTXT(child, level+1, cfg))
+	// Add read error (if not excluded and the node could not be read)
+	if !contains(cfg.ExcludeNodeFields, "error") && node.Error != "" {
+		parts = append(parts, "[error: "+node.Error+"]")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// nodeSize returns the size measure selected by cfg, disk usage or
+// apparent size, along with the name of the field it comes from
+func nodeSize(node *tree.Node, cfg *configs.FormatCfg) (int64, string) {
+	if cfg.DiskUsage {
+		return node.DiskUsage, "disk_usage"
+	}
+	return node.Size, "size"
+}
+
+// formatOwner formats the owner and group of a node as "user:group",
+// preferring names over numeric ids
+func formatOwner(node *tree.Node, excludeFields []string) string {
+	pick := func(name string, id *uint32, nameField, idField string) string {
+		if name != "" && !contains(excludeFields, nameField) {
+			return name
+		}
+		if id != nil && !contains(excludeFields, idField) {
+			return fmt.Sprintf("%d", *id)
 		}
+		return ""
 	}
 
-	return []byte(result.String())
+	owner := pick(node.Owner, node.UID, "owner", "uid")
+	group := pick(node.Group, node.GID, "group", "gid")
+	if owner == "" || group == "" {
+		return owner + group
+	}
+	return owner + ":" + group
 }
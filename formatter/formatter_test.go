@@ -1,7 +1,11 @@
 package formatter
 
 import (
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Maxim-Ba/dir-tree/configs"
 	"github.com/Maxim-Ba/dir-tree/tree"
@@ -131,6 +135,42 @@ func TestCreateFilteredNode(t *testing.T) {
 	}
 }
 
+// TestCreateFilteredNodeMetadata tests that every metadata field can be excluded
+func TestCreateFilteredNodeMetadata(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	uid, gid := uint32(0), uint32(0)
+	testNode := &tree.Node{
+		Name:       "test",
+		Type:       tree.File,
+		ModTime:    &modTime,
+		AccessTime: &modTime,
+		ChangeTime: &modTime,
+		Mode:       "-rw-r--r--",
+		Perm:       "0644",
+		UID:        &uid,
+		GID:        &gid,
+		Owner:      "root",
+		Group:      "root",
+		Inode:      42,
+		Device:     1,
+		Links:      2,
+		LinkTarget: "other",
+	}
+
+	kept := createFilteredNode(testNode, nil)
+	if kept.ModTime == nil || kept.UID == nil || kept.Owner != "root" || kept.Inode != 42 || kept.LinkTarget != "other" {
+		t.Errorf("Metadata should be kept without exclusions: %+v", kept)
+	}
+
+	excluded := createFilteredNode(testNode, []string{
+		"mod_time", "access_time", "change_time", "mode", "perm", "uid", "gid",
+		"owner", "group", "inode", "device", "links", "link_target",
+	})
+	expected := &filteredNode{Name: "test", Type: tree.File}
+	if !reflect.DeepEqual(excluded, expected) {
+		t.Errorf("createFilteredNode() = %+v, want %+v", excluded, expected)
+	}
+}
 
 // TestContains tests the helper function
 func TestContains(t *testing.T) {
@@ -215,3 +255,248 @@ func TestFormat_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestFormatTXTDiskUsage tests switching between apparent size and disk usage
+func TestFormatTXTDiskUsage(t *testing.T) {
+	node := &tree.Node{Name: "image.raw", Type: tree.File, Size: 1048576, DiskUsage: 4096}
+
+	apparent := string(formatTXT(node, &configs.FormatCfg{Type: configs.TXT}))
+	if !strings.Contains(apparent, "(1048576 bytes)") {
+		t.Errorf("Expected apparent size in %q", apparent)
+	}
+
+	usage := string(formatTXT(node, &configs.FormatCfg{Type: configs.TXT, DiskUsage: true}))
+	if !strings.Contains(usage, "(4096 bytes)") {
+		t.Errorf("Expected disk usage in %q", usage)
+	}
+}
+
+// TestFormatError tests output of nodes that could not be read
+func TestFormatError(t *testing.T) {
+	node := &tree.Node{Name: "locked", Type: tree.Directory, Error: "open locked: permission denied"}
+
+	text := string(formatTXT(node, &configs.FormatCfg{Type: configs.TXT}))
+	if !strings.Contains(text, "[error: open locked: permission denied]") {
+		t.Errorf("Expected error marker in %q", text)
+	}
+
+	excluded := string(formatTXT(node, &configs.FormatCfg{Type: configs.TXT, ExcludeNodeFields: []string{"error"}}))
+	if strings.Contains(excluded, "error") {
+		t.Errorf("Expected no error marker in %q", excluded)
+	}
+
+	data, err := Format(node, &configs.FormatCfg{Type: configs.JSON, ExcludeNodeFields: []string{"size"}})
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	if !strings.Contains(string(data), `"error":"open locked: permission denied"`) {
+		t.Errorf("Expected error field in %s", data)
+	}
+}
+
+// TestFormatTXTStyles tests the TXT styles and summary footer
+func TestFormatTXTStyles(t *testing.T) {
+	root := &tree.Node{
+		Name: "project",
+		Type: tree.Directory,
+		Children: []*tree.Node{
+			{
+				Name: "cmd",
+				Type: tree.Directory,
+				Children: []*tree.Node{
+					{Name: "main.go", Type: tree.File},
+				},
+			},
+			{
+				Name: "docs",
+				Type: tree.Directory,
+				Children: []*tree.Node{
+					{Name: "guide.md", Type: tree.File},
+				},
+			},
+			{Name: "go.mod", Type: tree.File},
+		},
+	}
+
+	tests := []struct {
+		style    configs.TXTStyle
+		expected string
+	}{
+		{
+			style: configs.UnicodeStyle,
+			expected: "project\n" +
+				"├── cmd\n" +
+				"│   └── main.go\n" +
+				"├── docs\n" +
+				"│   └── guide.md\n" +
+				"└── go.mod\n" +
+				"\n2 directories, 3 files\n",
+		},
+		{
+			style: configs.ASCIIStyle,
+			expected: "project\n" +
+				"|-- cmd\n" +
+				"|   `-- main.go\n" +
+				"|-- docs\n" +
+				"|   `-- guide.md\n" +
+				"`-- go.mod\n" +
+				"\n2 directories, 3 files\n",
+		},
+		{
+			style: configs.PlainStyle,
+			expected: "project\n" +
+				"  cmd\n" +
+				"    main.go\n" +
+				"  docs\n" +
+				"    guide.md\n" +
+				"  go.mod\n" +
+				"\n2 directories, 3 files\n",
+		},
+		{
+			style: configs.EmojiStyle,
+			expected: "📁  project\n" +
+				"  📁  cmd\n" +
+				"    📄  main.go\n" +
+				"  📁  docs\n" +
+				"    📄  guide.md\n" +
+				"  📄  go.mod\n" +
+				"\n2 directories, 3 files\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			result, err := Format(root, &configs.FormatCfg{Type: configs.TXT, Style: tt.style})
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+
+	single := string(formatTXT(root.Children[0], &configs.FormatCfg{Type: configs.TXT, Style: configs.PlainStyle}))
+	if !strings.HasSuffix(single, "\n0 directories, 1 file\n") {
+		t.Errorf("Expected singular summary in %q", single)
+	}
+}
+
+// TestFormatSize tests size presentation modes
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		format   configs.SizeFormat
+		expected string
+	}{
+		{1536, "", "1536 bytes"},
+		{1536, configs.BytesSize, "1536 bytes"},
+		{999, configs.SISize, "999 B"},
+		{1536, configs.SISize, "1.5 kB"},
+		{1536, configs.IECSize, "1.5 KiB"},
+		{5 << 30, configs.IECSize, "5.0 GiB"},
+		{2500000, configs.SISize, "2.5 MB"},
+		{1 << 20, "KiB", "1024.0 KiB"},
+		{512, "MiB", "0.0 MiB"},
+		{512, "B", "512 B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format)+" "+tt.expected, func(t *testing.T) {
+			if result := FormatSize(tt.size, tt.format); result != tt.expected {
+				t.Errorf("FormatSize(%d, %q) = %q, want %q", tt.size, tt.format, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestFormatSizeDetails tests human sizes, directory totals and percentages
+func TestFormatSizeDetails(t *testing.T) {
+	root := &tree.Node{
+		Name: "data",
+		Type: tree.Directory,
+		Size: 4096,
+		Children: []*tree.Node{
+			{Name: "a.bin", Type: tree.File, Size: 3072},
+			{Name: "b.bin", Type: tree.File, Size: 1024},
+		},
+	}
+	cfg := &configs.FormatCfg{Type: configs.TXT, Style: configs.PlainStyle, SizeFormat: configs.IECSize, Percent: true}
+
+	text := string(formatTXT(root, cfg))
+	expected := "data (4.0 KiB)\n  a.bin (3.0 KiB, 75.0%)\n  b.bin (1.0 KiB, 25.0%)\n\n0 directories, 2 files\n"
+	if text != expected {
+		t.Errorf("formatTXT() = %q, want %q", text, expected)
+	}
+
+	cfg.Type = configs.JSON
+	data, err := Format(root, cfg)
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	var decoded struct {
+		Size      int64  `json:"size"`
+		SizeHuman string `json:"size_human"`
+		Percent   *float64 `json:"percent"`
+		Children  []struct {
+			Size      int64    `json:"size"`
+			SizeHuman string   `json:"size_human"`
+			Percent   *float64 `json:"percent"`
+		} `json:"children"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded.Size != 4096 || decoded.SizeHuman != "4.0 KiB" || decoded.Percent != nil {
+		t.Errorf("Unexpected root sizes in %s", data)
+	}
+	if len(decoded.Children) != 2 || decoded.Children[0].Size != 3072 || decoded.Children[0].SizeHuman != "3.0 KiB" ||
+		decoded.Children[0].Percent == nil || *decoded.Children[0].Percent != 75 {
+		t.Errorf("Unexpected child sizes in %s", data)
+	}
+
+	cfg.ExcludeNodeFields = []string{"size_human", "percent"}
+	data, err = Format(root, cfg)
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	if strings.Contains(string(data), "size_human") || strings.Contains(string(data), "percent") {
+		t.Errorf("Expected excluded fields to be omitted from %s", data)
+	}
+}
+
+// TestFormatUnfilteredOptionalFields tests that YAML and XML written
+// without excluded fields leave out unset optional fields and name the
+// set ones like JSON does
+func TestFormatUnfilteredOptionalFields(t *testing.T) {
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	root := &tree.Node{Name: "root", Path: "root", Type: tree.Directory, Children: []*tree.Node{
+		{Name: "a.txt", Path: "root/a.txt", Type: tree.File, Size: 1, ModTime: &modTime},
+	}}
+
+	tests := []struct {
+		format   configs.OutputFormat
+		expected string
+	}{
+		{configs.YAML, "mod_time:"},
+		{configs.XML, "<mod_time>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			data, err := Format(root, &configs.FormatCfg{Type: tt.format})
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			output := strings.ToLower(string(data))
+			if strings.Count(output, tt.expected) != 1 {
+				t.Errorf("Expected one %q in %s", tt.expected, data)
+			}
+			for _, unset := range []string{"null", "diskusage", "disk_usage", "access_time", "cycle", "file_count", "uid"} {
+				if strings.Contains(output, unset) {
+					t.Errorf("Unexpected %q in %s", unset, data)
+				}
+			}
+		})
+	}
+}
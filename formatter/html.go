@@ -0,0 +1,66 @@
+package formatter
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// reportTemplate is the page of the HTML format, with {{title}} and
+// {{data}} placeholders
+//
+//go:embed report.html
+var reportTemplate string
+
+// htmlReport is the data the HTML report script renders
+type htmlReport struct {
+	Root      *filteredNode `json:"root"`
+	SizeField string        `json:"size_field,omitempty"` // Field shown in the size column, none if excluded
+	ModTime   bool          `json:"mod_time"`             // Whether to show the modification time column
+	Expand    int           `json:"expand"`               // Number of levels initially expanded
+}
+
+// formatHTML formats the tree as a standalone HTML page. The nodes are
+// embedded as the JSON format would write them and drawn by a script
+// that only creates the rows in view, so large trees stay responsive.
+func formatHTML(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+	expand, err := strconv.Atoi(cfg.Option("expand"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expand option: %w", err)
+	}
+
+	report := htmlReport{
+		Root:    createFilteredNode(node, cfg.ExcludeNodeFields),
+		ModTime: !contains(cfg.ExcludeNodeFields, "mod_time") && hasModTime(node),
+		Expand:  expand,
+	}
+	addSizeDetails(report.Root, node, nil, cfg)
+	if _, field := nodeSize(node, cfg); !contains(cfg.ExcludeNodeFields, field) {
+		report.SizeField = field
+	}
+
+	// json.Marshal escapes <, > and &, so the data cannot end the script
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	title := cfg.Option("title")
+	if title == "" {
+		title = node.Name
+	}
+	if title == "" {
+		title = "dir-tree"
+	}
+	page := strings.NewReplacer(
+		"{{title}}", html.EscapeString(title),
+		"{{data}}", string(data),
+	).Replace(reportTemplate)
+	return []byte(page), nil
+}
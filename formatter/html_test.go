@@ -0,0 +1,104 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// TestFormatHTML tests the data embedded in the HTML report
+func TestFormatHTML(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	root := &tree.Node{
+		Name: "<project>",
+		Path: "/project",
+		Type: tree.Directory,
+		Size: 2048,
+		Children: []*tree.Node{
+			{Name: "</script>.txt", Path: "/project/</script>.txt", Type: tree.File, Size: 2048, ModTime: &modTime},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		excludeFields []string
+		title         string
+		sizeField     string
+		modTime       bool
+		expand        int
+	}{
+		{
+			name:      "All fields",
+			title:     "&lt;project&gt;",
+			sizeField: "size",
+			modTime:   true,
+			expand:    1,
+		},
+		{
+			name:          "Excluded fields",
+			options:       map[string]string{"title": "Layout", "expand": "3"},
+			excludeFields: []string{"size", "mod_time", "path"},
+			title:         "Layout",
+			expand:        3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &configs.FormatCfg{Type: configs.HTML, ExcludeNodeFields: tt.excludeFields, SizeFormat: configs.IECSize, Options: tt.options}
+			result, err := Format(root, cfg)
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			page := string(result)
+			if !strings.Contains(page, "<title>"+tt.title+"</title>") {
+				t.Errorf("Page title is not %q", tt.title)
+			}
+			if strings.Count(page, "</script>") != 2 {
+				t.Errorf("Names are not escaped in the embedded data")
+			}
+
+			start := strings.Index(page, `<script id="report-data" type="application/json">`)
+			if start == -1 {
+				t.Fatal("Report data not found")
+			}
+			data := page[start:]
+			data = data[strings.Index(data, ">")+1 : strings.Index(data, "</script>")]
+			var report struct {
+				Root      map[string]any `json:"root"`
+				SizeField string         `json:"size_field"`
+				ModTime   bool           `json:"mod_time"`
+				Expand    int            `json:"expand"`
+			}
+			if err := json.Unmarshal([]byte(data), &report); err != nil {
+				t.Fatalf("Report data is not JSON: %v", err)
+			}
+			if report.SizeField != tt.sizeField || report.ModTime != tt.modTime || report.Expand != tt.expand {
+				t.Errorf("Report = %+v", report)
+			}
+
+			child := report.Root["children"].([]any)[0].(map[string]any)
+			if child["name"] != "</script>.txt" {
+				t.Errorf("Child name = %v", child["name"])
+			}
+			for _, field := range tt.excludeFields {
+				if _, ok := child[field]; ok {
+					t.Errorf("Excluded field %s is present", field)
+				}
+			}
+			if tt.sizeField != "" && child["size_human"] != "2.0 KiB" {
+				t.Errorf("Child size_human = %v", child["size_human"])
+			}
+		})
+	}
+
+	cfg := &configs.FormatCfg{Type: configs.HTML, Options: map[string]string{"expand": "all"}}
+	if _, err := Format(root, cfg); err == nil {
+		t.Error("Expected error for invalid expand option")
+	}
+}
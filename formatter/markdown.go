@@ -0,0 +1,211 @@
+package formatter
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// markdownEscaper escapes the characters Markdown would interpret in names
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// formatMarkdown formats the tree in the Markdown variant selected by the
+// "variant" option
+func formatMarkdown(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+	switch variant := configs.MarkdownVariant(cfg.Option("variant")); variant {
+	case configs.MarkdownTree:
+		return formatMarkdownTree(node, cfg), nil
+	case configs.MarkdownList:
+		return formatMarkdownList(node, cfg), nil
+	case configs.MarkdownTable:
+		return formatMarkdownTable(node, cfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported markdown variant: %s", variant)
+	}
+}
+
+// formatMarkdownTree formats the tree as a fenced code block drawn with
+// unicode connectors, unless another TXT style is chosen
+func formatMarkdownTree(node *tree.Node, cfg *configs.FormatCfg) []byte {
+	txt := *cfg
+	if txt.Style == "" {
+		txt.Style = configs.UnicodeStyle
+	}
+
+	var result strings.Builder
+	result.WriteString("```text\n")
+	writeTXTTree(&result, node, &txt, nil)
+	result.WriteString("```\n")
+	return []byte(result.String())
+}
+
+// formatMarkdownList formats the tree as a nested bullet list. With the
+// "links" option every entry links to its path relative to the root,
+// prefixed with the "link-base" option.
+func formatMarkdownList(node *tree.Node, cfg *configs.FormatCfg) []byte {
+	links := cfg.Option("links") == "true"
+	base := cfg.Option("link-base")
+	var result strings.Builder
+
+	var write func(node, parent *tree.Node, rel string, level int)
+	write = func(node, parent *tree.Node, rel string, level int) {
+		label := markdownName(node, cfg)
+		if links && !contains(cfg.ExcludeNodeFields, "name") {
+			label = "[" + label + "](" + markdownLink(base, rel, node.Type == tree.Directory) + ")"
+		}
+		if details := markdownDetails(node, parent, cfg); details != "" {
+			label += " " + details
+		}
+		result.WriteString(strings.Repeat("  ", level) + "- " + label + "\n")
+
+		if contains(cfg.ExcludeNodeFields, "children") {
+			return
+		}
+		for _, child := range node.Children {
+			write(child, node, path.Join(rel, child.Name), level+1)
+		}
+	}
+	write(node, nil, ".", 0)
+	return []byte(result.String())
+}
+
+// formatMarkdownTable formats the descendants of the root as a table.
+// Columns are left out when their field is excluded; the path column
+// falls back to names when paths are excluded, and modification times
+// are only shown when metadata was collected.
+func formatMarkdownTable(node *tree.Node, cfg *configs.FormatCfg) []byte {
+	type column struct {
+		title, align string
+		value        func(node, parent *tree.Node, rel string) string
+	}
+	var columns []column
+
+	switch {
+	case !contains(cfg.ExcludeNodeFields, "path"):
+		columns = append(columns, column{"Path", "---", func(node, _ *tree.Node, rel string) string {
+			if node.Type == tree.Directory {
+				rel += "/"
+			}
+			return "`" + strings.ReplaceAll(rel, "|", `\|`) + "`"
+		}})
+	case !contains(cfg.ExcludeNodeFields, "name"):
+		columns = append(columns, column{"Name", "---", func(node, _ *tree.Node, _ string) string {
+			return markdownEscaper.Replace(node.Name)
+		}})
+	}
+	if !contains(cfg.ExcludeNodeFields, "type") {
+		columns = append(columns, column{"Type", "---", func(node, _ *tree.Node, _ string) string {
+			return string(node.Type)
+		}})
+	}
+	if _, field := nodeSize(node, cfg); !contains(cfg.ExcludeNodeFields, field) {
+		columns = append(columns, column{"Size", "---:", func(node, parent *tree.Node, _ string) string {
+			return strings.Trim(formatSizeDetail(node, parent, cfg), "()")
+		}})
+	}
+	if !contains(cfg.ExcludeNodeFields, "mod_time") && hasModTime(node) {
+		columns = append(columns, column{"Modified", "---", func(node, _ *tree.Node, _ string) string {
+			if node.ModTime == nil {
+				return ""
+			}
+			return node.ModTime.Format("2006-01-02 15:04")
+		}})
+	}
+
+	var result strings.Builder
+	writeRow := func(cells []string) {
+		result.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	titles, aligns := make([]string, len(columns)), make([]string, len(columns))
+	for i, c := range columns {
+		titles[i], aligns[i] = c.title, c.align
+	}
+	writeRow(titles)
+	writeRow(aligns)
+
+	var write func(node *tree.Node, rel string)
+	write = func(dir *tree.Node, rel string) {
+		for _, child := range dir.Children {
+			childRel := path.Join(rel, child.Name)
+			cells := make([]string, len(columns))
+			for i, c := range columns {
+				cells[i] = c.value(child, dir, childRel)
+			}
+			writeRow(cells)
+			write(child, childRel)
+		}
+	}
+	if !contains(cfg.ExcludeNodeFields, "children") {
+		write(node, ".")
+	}
+	return []byte(result.String())
+}
+
+// markdownName returns the escaped name of a node, with a trailing slash
+// for directories
+func markdownName(node *tree.Node, cfg *configs.FormatCfg) string {
+	if contains(cfg.ExcludeNodeFields, "name") {
+		return ""
+	}
+	name := markdownEscaper.Replace(node.Name)
+	if node.Type == tree.Directory {
+		name += "/"
+	}
+	return name
+}
+
+// markdownDetails formats the size, modification time, link target and
+// error of a list entry, as far as they are not excluded
+func markdownDetails(node, parent *tree.Node, cfg *configs.FormatCfg) string {
+	var parts []string
+	if size, field := nodeSize(node, cfg); !contains(cfg.ExcludeNodeFields, field) && node.Type != tree.Symlink && size > 0 {
+		parts = append(parts, formatSizeDetail(node, parent, cfg))
+	}
+	if !contains(cfg.ExcludeNodeFields, "mod_time") && node.ModTime != nil {
+		parts = append(parts, node.ModTime.Format("2006-01-02 15:04"))
+	}
+	if !contains(cfg.ExcludeNodeFields, "target") && node.Target != "" {
+		parts = append(parts, "→ "+markdownEscaper.Replace(node.Target))
+	}
+	if !contains(cfg.ExcludeNodeFields, "error") && node.Error != "" {
+		parts = append(parts, "*error: "+markdownEscaper.Replace(node.Error)+"*")
+	}
+	return strings.Join(parts, " ")
+}
+
+// markdownLink returns the link target of the node at rel, escaping each
+// element of the path. The base is used as is, as it may be a URL.
+func markdownLink(base, rel string, dir bool) string {
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	target := strings.Join(parts, "/")
+	if dir {
+		target += "/"
+	}
+	if base == "" {
+		return target
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(target, "./")
+}
+
+// hasModTime reports whether any node of the tree has a modification time
+func hasModTime(node *tree.Node) bool {
+	if node.ModTime != nil {
+		return true
+	}
+	for _, child := range node.Children {
+		if hasModTime(child) {
+			return true
+		}
+	}
+	return false
+}
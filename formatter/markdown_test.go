@@ -0,0 +1,137 @@
+package formatter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// TestFormatMarkdown tests the Markdown variants and field exclusion
+func TestFormatMarkdown(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	root := &tree.Node{
+		Name: "project",
+		Path: "/project",
+		Type: tree.Directory,
+		Size: 15,
+		Children: []*tree.Node{
+			{
+				Name: "my docs",
+				Path: "/project/my docs",
+				Type: tree.Directory,
+				Size: 5,
+				Children: []*tree.Node{
+					{Name: "a_b.md", Path: "/project/my docs/a_b.md", Type: tree.File, Size: 5, ModTime: &modTime},
+				},
+			},
+			{Name: "main.go", Path: "/project/main.go", Type: tree.File, Size: 10},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		excludeFields []string
+		style         configs.TXTStyle
+		expected      string
+	}{
+		{
+			name: "Tree",
+			expected: "```text\n" +
+				"project (15 bytes)\n" +
+				"├── my docs (5 bytes)\n" +
+				"│   └── 2024-05-01 12:30 a_b.md (5 bytes)\n" +
+				"└── main.go (10 bytes)\n" +
+				"```\n",
+		},
+		{
+			name:          "Tree with ASCII style and without sizes",
+			excludeFields: []string{"size", "mod_time"},
+			style:         configs.ASCIIStyle,
+			expected: "```text\n" +
+				"project\n" +
+				"|-- my docs\n" +
+				"|   `-- a_b.md\n" +
+				"`-- main.go\n" +
+				"```\n",
+		},
+		{
+			name:          "Tree with plain style",
+			excludeFields: []string{"size", "mod_time"},
+			style:         configs.PlainStyle,
+			expected: "```text\n" +
+				"project\n" +
+				"  my docs\n" +
+				"    a_b.md\n" +
+				"  main.go\n" +
+				"```\n",
+		},
+		{
+			name:    "List",
+			options: map[string]string{"variant": "list"},
+			expected: "- project/ (15 bytes)\n" +
+				"  - my docs/ (5 bytes)\n" +
+				"    - a\\_b.md (5 bytes) 2024-05-01 12:30\n" +
+				"  - main.go (10 bytes)\n",
+		},
+		{
+			name:          "List with links",
+			options:       map[string]string{"variant": "list", "links": "true", "link-base": "https://example.com/tree/main"},
+			excludeFields: []string{"size", "mod_time"},
+			expected: "- [project/](https://example.com/tree/main/)\n" +
+				"  - [my docs/](https://example.com/tree/main/my%20docs/)\n" +
+				"    - [a\\_b.md](https://example.com/tree/main/my%20docs/a_b.md)\n" +
+				"  - [main.go](https://example.com/tree/main/main.go)\n",
+		},
+		{
+			name:          "List without children",
+			options:       map[string]string{"variant": "list"},
+			excludeFields: []string{"children"},
+			expected:      "- project/ (15 bytes)\n",
+		},
+		{
+			name:    "Table",
+			options: map[string]string{"variant": "table"},
+			expected: "| Path | Type | Size | Modified |\n" +
+				"| --- | --- | ---: | --- |\n" +
+				"| `my docs/` | directory | 5 bytes |  |\n" +
+				"| `my docs/a_b.md` | file | 5 bytes | 2024-05-01 12:30 |\n" +
+				"| `main.go` | file | 10 bytes |  |\n",
+		},
+		{
+			name:          "Table with excluded fields",
+			options:       map[string]string{"variant": "table"},
+			excludeFields: []string{"path", "size", "mod_time"},
+			expected: "| Name | Type |\n" +
+				"| --- | --- |\n" +
+				"| my docs | directory |\n" +
+				"| a\\_b.md | file |\n" +
+				"| main.go | file |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &configs.FormatCfg{
+				Type:              configs.Markdown,
+				ExcludeNodeFields: tt.excludeFields,
+				Style:             tt.style,
+				Options:           tt.options,
+			}
+			result, err := Format(root, cfg)
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+
+	cfg := &configs.FormatCfg{Type: configs.Markdown, Options: map[string]string{"variant": "html"}}
+	if _, err := Format(root, cfg); err == nil {
+		t.Error("Expected error for unknown variant")
+	}
+}
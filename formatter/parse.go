@@ -0,0 +1,259 @@
+package formatter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+	"gopkg.in/yaml.v2"
+)
+
+// Parser is implemented by formatters whose output can be read back
+type Parser interface {
+	// Parse reconstructs the tree from the output of Format
+	Parse(data []byte) (*tree.Node, error)
+}
+
+// Parse reconstructs a tree from data written in format, with or without
+// excluded fields; excluded fields are left empty. Derived fields such as
+// size_human and percent are ignored.
+func Parse(data []byte, format configs.OutputFormat) (*tree.Node, error) {
+	impl, ok := Lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	parser, ok := impl.(Parser)
+	if !ok {
+		return nil, fmt.Errorf("format %s cannot be parsed", format)
+	}
+	return parser.Parse(data)
+}
+
+// fieldNames maps field names without underscores, in lower case, to the
+// names used by filtered output. Trees formatted without excluded fields
+// use Go field names in YAML and XML, e.g. "IsHidden" for "is_hidden".
+var fieldNames = func() map[string]string {
+	names := map[string]string{}
+	t := reflect.TypeOf(filteredNode{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		names[strings.ReplaceAll(name, "_", "")] = name
+	}
+	return names
+}()
+
+// fieldName returns the filtered output name of a field in either form
+func fieldName(name string) string {
+	if canonical, ok := fieldNames[strings.ToLower(strings.ReplaceAll(name, "_", ""))]; ok {
+		return canonical
+	}
+	return name
+}
+
+// parseJSON reads a tree written by formatJSON
+func parseJSON(data []byte) (*tree.Node, error) {
+	var parsed filteredNode
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing json: %w", err)
+	}
+	return parsedNode(&parsed), nil
+}
+
+// parseNDJSON reads a tree written by the NDJSON encoder. The structure
+// is recovered from the paths, so they must not have been excluded. Paths
+// are cleaned before they are linked, so roots such as "." or "dir/" find
+// their children; nodes whose parent is missing are kept below the root.
+func parseNDJSON(data []byte) (*tree.Node, error) {
+	children := map[string][]*tree.Node{} // nodes waiting for their parent, by cleaned parent path
+	var parents []string                  // keys of children, in order of appearance
+	var root *tree.Node
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var parsed filteredNode
+		if err := json.Unmarshal(scanner.Bytes(), &parsed); err != nil {
+			return nil, fmt.Errorf("error parsing ndjson line %d: %w", line, err)
+		}
+		if parsed.Path == "" {
+			return nil, fmt.Errorf("error parsing ndjson line %d: node has no path", line)
+		}
+
+		// Like du, every directory follows its contents
+		node := parsedNode(&parsed)
+		key := filepath.Clean(filepath.FromSlash(node.Path))
+		node.Children = children[key]
+		delete(children, key)
+		parent := filepath.Dir(key)
+		if _, ok := children[parent]; !ok {
+			parents = append(parents, parent)
+		}
+		children[parent] = append(children[parent], node)
+		root = node
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error parsing ndjson: %w", err)
+	}
+	if root == nil {
+		return nil, fmt.Errorf("error parsing ndjson: no nodes")
+	}
+
+	// The last node is the root; anything else still waiting is top level
+	for _, parent := range parents {
+		for _, node := range children[parent] {
+			if node != root {
+				root.Children = append(root.Children, node)
+			}
+		}
+		delete(children, parent)
+	}
+	return root, nil
+}
+
+// parseYAML reads a tree written by formatYAML
+func parseYAML(data []byte) (*tree.Node, error) {
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error parsing yaml: %w", err)
+	}
+
+	// Rename the fields of unfiltered output before decoding
+	normalized, err := yaml.Marshal(normalizeYAML(document))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing yaml: %w", err)
+	}
+	var parsed filteredNode
+	if err := yaml.Unmarshal(normalized, &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing yaml: %w", err)
+	}
+	return parsedNode(&parsed), nil
+}
+
+// normalizeYAML renames the fields of a YAML node and its children to
+// the names of filtered output
+func normalizeYAML(node yaml.MapSlice) yaml.MapSlice {
+	result := make(yaml.MapSlice, 0, len(node))
+	for _, item := range node {
+		key := fieldName(fmt.Sprint(item.Key))
+		value := item.Value
+		if children, ok := value.([]interface{}); ok && key == "children" {
+			normalized := make([]interface{}, 0, len(children))
+			for _, child := range children {
+				if child, ok := child.(yaml.MapSlice); ok {
+					normalized = append(normalized, normalizeYAML(child))
+				}
+			}
+			value = normalized
+		}
+		result = append(result, yaml.MapItem{Key: key, Value: value})
+	}
+	return result
+}
+
+// xmlElement is an XML element read without a schema
+type xmlElement struct {
+	XMLName  xml.Name
+	Content  string       `xml:",chardata"`
+	Children []xmlElement `xml:",any"`
+}
+
+// parseXML reads a tree written by formatXML or the XML encoder
+func parseXML(data []byte) (*tree.Node, error) {
+	var document xmlElement
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error parsing xml: %w", err)
+	}
+
+	// Rewrite unfiltered output, whose children are repeated <Children>
+	// elements, into the layout of filtered output before decoding
+	normalized, err := xml.Marshal(normalizeXML(document))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing xml: %w", err)
+	}
+	var parsed filteredNode
+	if err := xml.Unmarshal(normalized, &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing xml: %w", err)
+	}
+	return parsedNode(&parsed), nil
+}
+
+// normalizeXML rewrites the element of a node into a <node> element
+// with the field names and <children> wrapper of filtered output
+func normalizeXML(element xmlElement) xmlElement {
+	result := xmlElement{XMLName: xml.Name{Local: "node"}}
+	var children []xmlElement
+
+	for _, field := range element.Children {
+		name := fieldName(field.XMLName.Local)
+		if name != "children" {
+			result.Children = append(result.Children, xmlElement{XMLName: xml.Name{Local: name}, Content: field.Content})
+			continue
+		}
+
+		// Filtered output wraps the child <node> elements, unfiltered
+		// output has one <Children> element per child
+		wrapper := len(field.Children) > 0
+		for _, child := range field.Children {
+			wrapper = wrapper && child.XMLName.Local == "node"
+		}
+		switch {
+		case wrapper:
+			for _, child := range field.Children {
+				children = append(children, normalizeXML(child))
+			}
+		case len(field.Children) > 0:
+			children = append(children, normalizeXML(field))
+		}
+	}
+
+	if len(children) > 0 {
+		result.Children = append(result.Children, xmlElement{XMLName: xml.Name{Local: "children"}, Children: children})
+	}
+	return result
+}
+
+// parsedNode converts a decoded node and its children to tree nodes
+func parsedNode(parsed *filteredNode) *tree.Node {
+	node := &tree.Node{
+		Name:       parsed.Name,
+		Path:       parsed.Path,
+		Type:       parsed.Type,
+		Size:       parsed.Size,
+		DiskUsage:  parsed.DiskUsage,
+		IsHidden:   parsed.IsHidden,
+		Error:      parsed.Error,
+		Target:     parsed.Target,
+		Cycle:      parsed.Cycle,
+		Hash:       parsed.Hash,
+		FileCount:  parsed.FileCount,
+		DirCount:   parsed.DirCount,
+		MaxDepth:   parsed.MaxDepth,
+		ModTime:    parsed.ModTime,
+		AccessTime: parsed.AccessTime,
+		ChangeTime: parsed.ChangeTime,
+		Mode:       parsed.Mode,
+		Perm:       parsed.Perm,
+		UID:        parsed.UID,
+		GID:        parsed.GID,
+		Owner:      parsed.Owner,
+		Group:      parsed.Group,
+		Inode:      parsed.Inode,
+		Device:     parsed.Device,
+		Links:      parsed.Links,
+		LinkTarget: parsed.LinkTarget,
+	}
+	for _, child := range parsed.Children {
+		node.Children = append(node.Children, parsedNode(child))
+	}
+	return node
+}
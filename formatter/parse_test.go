@@ -0,0 +1,221 @@
+package formatter
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// parseTree is a tree using every node field
+func parseTree() *tree.Node {
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	uid, gid := uint32(1000), uint32(100)
+	return &tree.Node{
+		Name: "project", Path: "project", Type: tree.Directory,
+		Size: 30, DiskUsage: 8192, FileCount: 3, DirCount: 2, MaxDepth: 2,
+		ModTime: &modTime, Mode: "drwxr-xr-x", Perm: "0755", UID: &uid, GID: &gid, Owner: "dev", Group: "users",
+		Children: []*tree.Node{
+			{
+				Name: "src", Path: "project/src", Type: tree.Directory,
+				Size: 20, DiskUsage: 4096, FileCount: 1, MaxDepth: 1,
+				Children: []*tree.Node{
+					{Name: "main.go", Path: "project/src/main.go", Type: tree.File, Size: 20, DiskUsage: 4096,
+						ModTime: &modTime, AccessTime: &modTime, ChangeTime: &modTime, Inode: 42, Device: 7, Links: 2,
+						Hash: "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"},
+				},
+			},
+			{Name: "locked", Path: "project/locked", Type: tree.Directory, Error: "permission denied"},
+			{Name: ".env", Path: "project/.env", Type: tree.File, Size: 10, IsHidden: true},
+			{Name: "link", Path: "project/link", Type: tree.Symlink, Size: 3, Target: "project", LinkTarget: "..", Cycle: true},
+		},
+	}
+}
+
+// TestParseRoundTrip tests that formatted trees are parsed back unchanged
+func TestParseRoundTrip(t *testing.T) {
+	formats := []configs.OutputFormat{configs.JSON, configs.YAML, configs.XML, configs.NDJSON}
+	configsByName := map[string]configs.FormatCfg{
+		"Plain":    {},
+		"Indented": {Indent: 4},
+		"Decorated": {
+			SizeFormat: configs.IECSize,
+			Percent:    true,
+		},
+	}
+
+	for _, format := range formats {
+		for name, cfg := range configsByName {
+			t.Run(string(format)+"/"+name, func(t *testing.T) {
+				cfg.Type = format
+				data, err := Format(parseTree(), &cfg)
+				if err != nil {
+					t.Fatalf("Format returned error: %v", err)
+				}
+				result, err := Parse(data, format)
+				if err != nil {
+					t.Fatalf("Parse returned error: %v\n%s", err, data)
+				}
+				if !reflect.DeepEqual(result, parseTree()) {
+					t.Errorf("Parse(Format()) =\n%s\nwant\n%s", dump(result), dump(parseTree()))
+				}
+			})
+		}
+	}
+}
+
+// TestParseNDJSONRoots tests that NDJSON of trees whose root path is not
+// clean, such as the default ".", is parsed back with its structure
+func TestParseNDJSONRoots(t *testing.T) {
+	for _, root := range []string{".", "/tmp/rv/", "./project"} {
+		t.Run(root, func(t *testing.T) {
+			join := func(elem ...string) string { return filepath.Join(append([]string{root}, elem...)...) }
+			expected := &tree.Node{
+				Name: "rv", Path: root, Type: tree.Directory, Size: 3, FileCount: 2, DirCount: 1, MaxDepth: 2,
+				Children: []*tree.Node{
+					{Name: "a.txt", Path: join("a.txt"), Type: tree.File, Size: 1},
+					{Name: "sub", Path: join("sub"), Type: tree.Directory, Size: 2, FileCount: 1, MaxDepth: 1,
+						Children: []*tree.Node{{Name: "b.txt", Path: join("sub", "b.txt"), Type: tree.File, Size: 2}}},
+				},
+			}
+			data, err := Format(expected, &configs.FormatCfg{Type: configs.NDJSON})
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			result, err := Parse(data, configs.NDJSON)
+			if err != nil {
+				t.Fatalf("Parse returned error: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Parse(Format()) =\n%s\nwant\n%s", dump(result), dump(expected))
+			}
+		})
+	}
+}
+
+// TestParseStreamed tests that streamed output is parsed like formatted output
+func TestParseStreamed(t *testing.T) {
+	for _, format := range []configs.OutputFormat{configs.JSON, configs.YAML, configs.XML} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := NewEncoder(&buf, &configs.FormatCfg{Type: format, Indent: 2})
+			if err != nil {
+				t.Fatalf("NewEncoder returned error: %v", err)
+			}
+			if err := encodeTree(enc, parseTree()); err != nil {
+				t.Fatalf("Encoding returned error: %v", err)
+			}
+			result, err := Parse(buf.Bytes(), format)
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+			if !reflect.DeepEqual(result, parseTree()) {
+				t.Errorf("Parse() =\n%s\nwant\n%s", dump(result), dump(parseTree()))
+			}
+		})
+	}
+}
+
+// TestParseFiltered tests parsing output with excluded fields
+func TestParseFiltered(t *testing.T) {
+	cfg := configs.FormatCfg{ExcludeNodeFields: []string{"size", "disk_usage", "mod_time", "uid", "gid", "owner", "group", "mode", "perm",
+		"access_time", "change_time", "inode", "device", "links", "link_target", "file_count", "dir_count", "max_depth", "error", "target", "cycle",
+		"hash"}}
+	expected := &tree.Node{
+		Name: "project", Path: "project", Type: tree.Directory,
+		Children: []*tree.Node{
+			{
+				Name: "src", Path: "project/src", Type: tree.Directory,
+				Children: []*tree.Node{
+					{Name: "main.go", Path: "project/src/main.go", Type: tree.File},
+				},
+			},
+			{Name: "locked", Path: "project/locked", Type: tree.Directory},
+			{Name: ".env", Path: "project/.env", Type: tree.File, IsHidden: true},
+			{Name: "link", Path: "project/link", Type: tree.Symlink},
+		},
+	}
+
+	for _, format := range []configs.OutputFormat{configs.JSON, configs.YAML, configs.XML, configs.NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			cfg.Type = format
+			data, err := Format(parseTree(), &cfg)
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			result, err := Parse(data, format)
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Parse() =\n%s\nwant\n%s", dump(result), dump(expected))
+			}
+		})
+	}
+
+	// Without children only the root remains
+	cfg = configs.FormatCfg{Type: configs.YAML, ExcludeNodeFields: []string{"children", "mod_time", "uid", "gid"}}
+	data, err := Format(parseTree(), &cfg)
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	result, err := Parse(data, configs.YAML)
+	if err != nil || result.Name != "project" || result.Size != 30 || result.Children != nil {
+		t.Errorf("Parse() without children = %+v, %v", result, err)
+	}
+}
+
+// TestParseErrors tests invalid input and formats that cannot be parsed
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format configs.OutputFormat
+	}{
+		{"TXT", "project\n", configs.TXT},
+		{"Unknown", "{}", "unknown"},
+		{"InvalidJSON", "{", configs.JSON},
+		{"InvalidYAML", "name: [", configs.YAML},
+		{"InvalidXML", "<node>", configs.XML},
+		{"EmptyNDJSON", "\n", configs.NDJSON},
+		{"NDJSONWithoutPaths", `{"name":"a"}`, configs.NDJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data), tt.format); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// dump lists the nodes of a tree for error messages
+func dump(node *tree.Node) string {
+	var b strings.Builder
+	var walk func(*tree.Node, string)
+	walk = func(n *tree.Node, indent string) {
+		children := n.Children
+		n.Children = nil
+		b.WriteString(indent)
+		b.WriteString(strings.TrimSpace(strings.ReplaceAll(string(mustFormat(n)), "\n", " ")))
+		b.WriteString("\n")
+		n.Children = children
+		for _, child := range children {
+			walk(child, indent+"  ")
+		}
+	}
+	walk(node, "")
+	return b.String()
+}
+
+// mustFormat formats a node as JSON, ignoring errors
+func mustFormat(node *tree.Node) []byte {
+	data, _ := formatJSON(node, &configs.FormatCfg{})
+	return data
+}
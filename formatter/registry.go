@@ -0,0 +1,109 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// Formatter renders a directory tree in an output format
+type Formatter interface {
+	// Format renders the tree rooted at node
+	Format(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error)
+	// Descriptor declares the file extension, options and streaming
+	// capability of the format
+	Descriptor() configs.FormatDescriptor
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[configs.OutputFormat]Formatter{}
+)
+
+// Register makes a formatter available under name to Format, and to
+// Config.Validate and FormatCfg.GetOutputPath. It panics if name is
+// empty, impl is nil or a formatter is already registered under name,
+// which includes the built-in formats.
+func Register(name configs.OutputFormat, impl Formatter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" || impl == nil {
+		panic("formatter: Register with empty name or nil formatter")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("formatter: Register called twice for format %s", name))
+	}
+	configs.RegisterFormat(name, impl.Descriptor())
+	registry[name] = impl
+}
+
+// Lookup returns the formatter registered under name
+func Lookup(name configs.OutputFormat) (Formatter, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	impl, ok := registry[name]
+	return impl, ok
+}
+
+// builtin adapts the formatting functions of this package to
+// StreamFormatter and Parser
+type builtin struct {
+	format     func(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error)
+	encoder    func(w io.Writer, cfg *configs.FormatCfg) Encoder
+	parse      func(data []byte) (*tree.Node, error) // nil if the format cannot be read back
+	descriptor configs.FormatDescriptor
+}
+
+// Format implements Formatter
+func (b builtin) Format(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+	return b.format(node, cfg)
+}
+
+// Descriptor implements Formatter
+func (b builtin) Descriptor() configs.FormatDescriptor {
+	return b.descriptor
+}
+
+// NewEncoder implements StreamFormatter
+func (b builtin) NewEncoder(w io.Writer, cfg *configs.FormatCfg) Encoder {
+	return b.encoder(w, cfg)
+}
+
+// Parse implements Parser
+func (b builtin) Parse(data []byte) (*tree.Node, error) {
+	if b.parse == nil {
+		return nil, fmt.Errorf("format %s cannot be parsed", b.descriptor.Extension)
+	}
+	return b.parse(data)
+}
+
+// registerBuiltin makes the implementation of a built-in format, whose
+// descriptor the configs package declares, available to Format
+func registerBuiltin(name configs.OutputFormat, b builtin) {
+	descriptor, ok := configs.LookupFormat(name)
+	if !ok {
+		panic(fmt.Sprintf("formatter: no descriptor for built-in format %s", name))
+	}
+	b.descriptor = descriptor
+	registry[name] = b
+}
+
+func init() {
+	registerBuiltin(configs.JSON, builtin{format: formatJSON, encoder: newJSONEncoder, parse: parseJSON})
+	registerBuiltin(configs.YAML, builtin{format: formatYAML, encoder: newYAMLEncoder, parse: parseYAML})
+	registerBuiltin(configs.XML, builtin{format: formatXML, encoder: newXMLEncoder, parse: parseXML})
+	registerBuiltin(configs.TXT, builtin{
+		format: func(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+			return formatTXT(node, cfg), nil
+		},
+		encoder: newTXTEncoder,
+	})
+	registerBuiltin(configs.NDJSON, builtin{format: formatStream(newNDJSONEncoder), encoder: newNDJSONEncoder, parse: parseNDJSON})
+	registerBuiltin(configs.Markdown, builtin{format: formatMarkdown})
+	registerBuiltin(configs.HTML, builtin{format: formatHTML})
+	registerBuiltin(configs.SVG, builtin{format: formatSVG})
+	registerBuiltin(configs.DOT, builtin{format: formatDOT})
+}
@@ -0,0 +1,111 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// namesFormatter is a test format listing node names
+type namesFormatter struct{}
+
+// Format implements Formatter
+func (namesFormatter) Format(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+	var names []string
+	var walk func(*tree.Node)
+	walk = func(n *tree.Node) {
+		names = append(names, n.Name)
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+	return []byte(strings.Join(names, cfg.Option("separator"))), nil
+}
+
+// Descriptor implements Formatter
+func (namesFormatter) Descriptor() configs.FormatDescriptor {
+	return configs.FormatDescriptor{
+		Extension: "names",
+		Options: []configs.FormatOption{
+			{Name: "separator", Description: "Text between names", Default: ","},
+			{Name: "case", Description: "Letter case", Values: []string{"upper", "lower"}},
+		},
+	}
+}
+
+// TestRegister tests adding a custom format
+func TestRegister(t *testing.T) {
+	const name configs.OutputFormat = "names-test"
+	registerForTest(t, name, namesFormatter{})
+
+	root := &tree.Node{Name: "root", Type: tree.Directory, Children: []*tree.Node{{Name: "a", Type: tree.File}}}
+	data, err := Format(root, &configs.FormatCfg{Type: name})
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	if string(data) != "root,a" {
+		t.Errorf("Format() = %q, want %q", data, "root,a")
+	}
+
+	data, err = Format(root, &configs.FormatCfg{Type: name, Options: map[string]string{"separator": "/"}})
+	if err != nil || string(data) != "root/a" {
+		t.Errorf("Format() with option = %q, %v, want %q", data, err, "root/a")
+	}
+
+	cfg := configs.New().WithFormat(name).WithOutputPath("out").Build()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned error for registered format: %v", err)
+	}
+	if path := cfg.Format.GetOutputPath(); path != "out.names" {
+		t.Errorf("GetOutputPath() = %q, want out.names", path)
+	}
+
+	for _, options := range []map[string]string{{"unknown": "x"}, {"case": "title"}} {
+		cfg.Format.Options = options
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected error for options %v", options)
+		}
+	}
+	cfg.Format.Options = map[string]string{"case": "upper"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() returned error for valid option: %v", err)
+	}
+
+	found := false
+	for _, format := range configs.Formats() {
+		found = found || format == name
+	}
+	if !found {
+		t.Errorf("Formats() = %v, want it to include %s", configs.Formats(), name)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for duplicate registration")
+		}
+	}()
+	Register(configs.JSON, namesFormatter{})
+}
+
+// registerForTest registers impl under name until the end of the test,
+// so that tests can run more than once
+func registerForTest(t *testing.T, name configs.OutputFormat, impl Formatter) {
+	t.Helper()
+	Register(name, impl)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+		configs.UnregisterFormat(name)
+	})
+}
+
+// TestFormatUnsupported tests formatting with an unknown format
+func TestFormatUnsupported(t *testing.T) {
+	if _, err := Format(&tree.Node{Name: "root"}, &configs.FormatCfg{Type: "unknown"}); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
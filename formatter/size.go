@@ -0,0 +1,95 @@
+package formatter
+
+import (
+	"fmt"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// siUnits and iecUnits are the units of automatically scaled sizes
+var (
+	siUnits  = []configs.SizeFormat{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	iecUnits = []configs.SizeFormat{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+// FormatSize formats a byte count as selected by format: raw bytes
+// ("1536 bytes"), SI ("1.5 kB"), IEC ("1.5 KiB") or a fixed unit
+func FormatSize(size int64, format configs.SizeFormat) string {
+	var units []configs.SizeFormat
+	var base float64
+	switch format {
+	case "", configs.BytesSize:
+		return fmt.Sprintf("%d bytes", size)
+	case configs.SISize:
+		units, base = siUnits, 1000
+	case configs.IECSize:
+		units, base = iecUnits, 1024
+	default:
+		unit, ok := configs.SizeUnits[format]
+		if !ok || unit == 1 {
+			return fmt.Sprintf("%d %s", size, format)
+		}
+		return fmt.Sprintf("%.1f %s", float64(size)/float64(unit), format)
+	}
+
+	value, i := float64(size), 0
+	for (value >= base || value <= -base) && i < len(units)-1 {
+		value /= base
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d %s", size, units[0])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
+// humanSizes reports whether cfg asks for sizes other than raw bytes
+func humanSizes(cfg *configs.FormatCfg) bool {
+	return cfg.SizeFormat != "" && cfg.SizeFormat != configs.BytesSize
+}
+
+// percentOf returns the share of node in the size of parent, as selected
+// by cfg, and whether it is available
+func percentOf(node, parent *tree.Node, cfg *configs.FormatCfg) (float64, bool) {
+	if parent == nil {
+		return 0, false
+	}
+	size, _ := nodeSize(node, cfg)
+	total, _ := nodeSize(parent, cfg)
+	if total <= 0 {
+		return 0, false
+	}
+	return 100 * float64(size) / float64(total), true
+}
+
+// formatSizeDetail formats the size of node for the TXT renderer, with
+// its percentage of the parent when requested, e.g. "(1.5 MiB, 12.5%)"
+func formatSizeDetail(node, parent *tree.Node, cfg *configs.FormatCfg) string {
+	size, _ := nodeSize(node, cfg)
+	detail := FormatSize(size, cfg.SizeFormat)
+	if percent, ok := percentOf(node, parent, cfg); ok && cfg.Percent {
+		detail += fmt.Sprintf(", %.1f%%", percent)
+	}
+	return "(" + detail + ")"
+}
+
+// addSizeDetails fills the human-readable size and percentage fields of
+// filtered, which was created from node, and of its descendants
+func addSizeDetails(filtered *filteredNode, node, parent *tree.Node, cfg *configs.FormatCfg) {
+	if humanSizes(cfg) {
+		if !contains(cfg.ExcludeNodeFields, "size_human") && !contains(cfg.ExcludeNodeFields, "size") {
+			filtered.SizeHuman = FormatSize(node.Size, cfg.SizeFormat)
+		}
+		if !contains(cfg.ExcludeNodeFields, "disk_usage_human") && !contains(cfg.ExcludeNodeFields, "disk_usage") && node.DiskUsage > 0 {
+			filtered.DiskUsageHuman = FormatSize(node.DiskUsage, cfg.SizeFormat)
+		}
+	}
+	if percent, ok := percentOf(node, parent, cfg); ok && cfg.Percent && !contains(cfg.ExcludeNodeFields, "percent") {
+		filtered.Percent = &percent
+	}
+
+	for i, child := range filtered.Children {
+		addSizeDetails(child, node.Children[i], node, cfg)
+	}
+}
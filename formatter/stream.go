@@ -0,0 +1,547 @@
+package formatter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+	"gopkg.in/yaml.v2"
+)
+
+// Encoder writes a tree node by node while tree.WalkTree visits it.
+// Directory totals are written once the directory is left, so in the
+// structured formats they follow the children, and TXT shows no
+// directory sizes. Encoders use the field names of filtered output.
+type Encoder interface {
+	tree.Visitor
+	// Close writes what follows the last node and flushes the output
+	Close() error
+}
+
+// StreamFormatter is a Formatter that can also write a tree while it is
+// being scanned. Formats implementing it should set Streaming in their
+// descriptor.
+type StreamFormatter interface {
+	Formatter
+	// NewEncoder returns an Encoder writing to w
+	NewEncoder(w io.Writer, cfg *configs.FormatCfg) Encoder
+}
+
+// NewEncoder returns an Encoder for the format selected by cfg.Type
+func NewEncoder(w io.Writer, cfg *configs.FormatCfg) (Encoder, error) {
+	impl, ok := Lookup(cfg.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported format: %s", cfg.Type)
+	}
+	streaming, ok := impl.(StreamFormatter)
+	if !ok {
+		return nil, fmt.Errorf("format %s does not support streaming", cfg.Type)
+	}
+	if cfg.Percent {
+		return nil, fmt.Errorf("percentages are not supported while streaming")
+	}
+	return streaming.NewEncoder(w, cfg), nil
+}
+
+// encodeTree writes a built tree through enc and closes it
+func encodeTree(enc Encoder, node *tree.Node) error {
+	var walk func(node *tree.Node, depth int, last bool) error
+	walk = func(node *tree.Node, depth int, last bool) error {
+		if err := enc.Enter(node, depth, last); err != nil {
+			return err
+		}
+		for i, child := range node.Children {
+			if err := walk(child, depth+1, i == len(node.Children)-1); err != nil {
+				return err
+			}
+		}
+		return enc.Leave(node, depth)
+	}
+
+	if err := walk(node, 0, true); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// formatStream formats a built tree with the encoder of a streaming format
+func formatStream(newEncoder func(w io.Writer, cfg *configs.FormatCfg) Encoder) func(*tree.Node, *configs.FormatCfg) ([]byte, error) {
+	return func(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := encodeTree(newEncoder(&buf, cfg), node); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// streamFrame tracks a node whose end has not been written yet
+type streamFrame struct {
+	children int  // number of children written so far
+	fields   bool // whether any field has been written
+}
+
+// streamEncoder holds the state shared by the encoders of this package
+type streamEncoder struct {
+	w     *bufio.Writer
+	cfg   *configs.FormatCfg
+	stack []streamFrame
+}
+
+// newStreamEncoder creates the shared encoder state
+func newStreamEncoder(w io.Writer, cfg *configs.FormatCfg) streamEncoder {
+	return streamEncoder{w: bufio.NewWriter(w), cfg: cfg}
+}
+
+// skip reports whether nodes at depth are left out of the output
+func (e *streamEncoder) skip(depth int) bool {
+	return depth > 0 && contains(e.cfg.ExcludeNodeFields, "children")
+}
+
+// push starts a child of the innermost open node, returning the parent's
+// frame, or nil for the root
+func (e *streamEncoder) push() *streamFrame {
+	e.stack = append(e.stack, streamFrame{})
+	if len(e.stack) == 1 {
+		return nil
+	}
+	return &e.stack[len(e.stack)-2]
+}
+
+// pop ends the innermost open node and returns its frame
+func (e *streamEncoder) pop() streamFrame {
+	frame := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	return frame
+}
+
+// write writes s. The bufio.Writer ignores writes after the first error
+// and returns that error from the Flush in Close.
+func (e *streamEncoder) write(s string) {
+	e.w.WriteString(s)
+}
+
+// headFields returns the fields of node known when it is entered: all of
+// them for files, and everything but the totals for directories
+func headFields(node *tree.Node, cfg *configs.FormatCfg) *filteredNode {
+	head := *node
+	head.Children = nil
+	if node.Type == tree.Directory {
+		head.Size, head.DiskUsage, head.FileCount, head.DirCount, head.MaxDepth = 0, 0, 0, 0, 0
+		return createFilteredNode(&head, cfg.ExcludeNodeFields)
+	}
+	filtered := createFilteredNode(&head, cfg.ExcludeNodeFields)
+	addSizeDetails(filtered, &head, nil, cfg)
+	return filtered
+}
+
+// totalFields returns the totals of a directory, known when it is left
+func totalFields(node *tree.Node, cfg *configs.FormatCfg) *filteredNode {
+	totals := *node
+	totals.Children = nil
+	filtered := createFilteredNode(&totals, cfg.ExcludeNodeFields)
+	addSizeDetails(filtered, &totals, nil, cfg)
+	return &filteredNode{
+		Size:           filtered.Size,
+		DiskUsage:      filtered.DiskUsage,
+		SizeHuman:      filtered.SizeHuman,
+		DiskUsageHuman: filtered.DiskUsageHuman,
+		FileCount:      filtered.FileCount,
+		DirCount:       filtered.DirCount,
+		MaxDepth:       filtered.MaxDepth,
+	}
+}
+
+// fullFields returns all fields of node except its children
+func fullFields(node *tree.Node, cfg *configs.FormatCfg) *filteredNode {
+	full := *node
+	full.Children = nil
+	filtered := createFilteredNode(&full, cfg.ExcludeNodeFields)
+	addSizeDetails(filtered, &full, nil, cfg)
+	return filtered
+}
+
+// jsonEncoder streams a tree as a single JSON document, laid out like
+// the output of formatJSON
+type jsonEncoder struct {
+	streamEncoder
+	indent string
+}
+
+// newJSONEncoder creates a JSON Encoder
+func newJSONEncoder(w io.Writer, cfg *configs.FormatCfg) Encoder {
+	return &jsonEncoder{streamEncoder: newStreamEncoder(w, cfg), indent: strings.Repeat(" ", cfg.Indent)}
+}
+
+// newline returns the line break and indentation of a nesting level,
+// or nothing when the output is compact
+func (e *jsonEncoder) newline(level int) string {
+	if e.indent == "" {
+		return ""
+	}
+	return "\n" + strings.Repeat(e.indent, level)
+}
+
+// fields marshals v for a node at depth and returns its members without
+// the enclosing braces
+func (e *jsonEncoder) fields(v *filteredNode, depth int) (string, error) {
+	var data []byte
+	var err error
+	if e.indent == "" {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, strings.Repeat(e.indent, 2*depth), e.indent)
+	}
+	if err != nil {
+		return "", err
+	}
+	members := string(data[1 : len(data)-1])
+	return strings.TrimSuffix(members, e.newline(2*depth)), nil
+}
+
+// Enter implements tree.Visitor
+func (e *jsonEncoder) Enter(node *tree.Node, depth int, last bool) error {
+	if e.skip(depth) {
+		return nil
+	}
+	head, err := e.fields(headFields(node, e.cfg), depth)
+	if err != nil {
+		return err
+	}
+
+	if parent := e.push(); parent != nil {
+		switch {
+		case parent.children > 0:
+			e.write(",")
+		case parent.fields:
+			e.write(",")
+			fallthrough
+		default:
+			colon := ":"
+			if e.indent != "" {
+				colon = ": "
+			}
+			e.write(e.newline(2*depth-1) + `"children"` + colon + "[")
+		}
+		parent.children++
+		e.write(e.newline(2 * depth))
+	}
+	e.write("{" + head)
+	e.stack[len(e.stack)-1].fields = head != ""
+	return nil
+}
+
+// Leave implements tree.Visitor
+func (e *jsonEncoder) Leave(node *tree.Node, depth int) error {
+	if e.skip(depth) {
+		return nil
+	}
+	frame := e.pop()
+	if frame.children > 0 {
+		e.write(e.newline(2*depth+1) + "]")
+	}
+	if node.Type == tree.Directory {
+		totals, err := e.fields(totalFields(node, e.cfg), depth)
+		if err != nil {
+			return err
+		}
+		if totals != "" {
+			if frame.fields || frame.children > 0 {
+				e.write(",")
+			}
+			e.write(totals)
+			frame.fields = true
+		}
+	}
+	if frame.fields || frame.children > 0 {
+		e.write(e.newline(2 * depth))
+	}
+	e.write("}")
+	return nil
+}
+
+// Close implements Encoder
+func (e *jsonEncoder) Close() error {
+	e.write("\n")
+	return e.w.Flush()
+}
+
+// yamlEncoder streams a tree as a YAML document, laid out like the
+// output of formatYAML
+type yamlEncoder struct {
+	streamEncoder
+	started []bool // whether the first line of each open node was written
+}
+
+// newYAMLEncoder creates a YAML Encoder
+func newYAMLEncoder(w io.Writer, cfg *configs.FormatCfg) Encoder {
+	return &yamlEncoder{streamEncoder: newStreamEncoder(w, cfg)}
+}
+
+// lines writes the lines of the node at depth. The first line of a
+// child node starts its sequence item.
+func (e *yamlEncoder) lines(depth int, lines []string) {
+	indent := strings.Repeat("  ", depth)
+	for _, line := range lines {
+		if depth > 0 && !e.started[depth] {
+			e.write(indent[2:] + "- " + line + "\n")
+		} else {
+			e.write(indent + line + "\n")
+		}
+		e.started[depth] = true
+	}
+}
+
+// yamlLines marshals v and splits the result into lines
+func yamlLines(v *filteredNode) ([]string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "{}" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// Enter implements tree.Visitor
+func (e *yamlEncoder) Enter(node *tree.Node, depth int, last bool) error {
+	if e.skip(depth) {
+		return nil
+	}
+	head, err := yamlLines(headFields(node, e.cfg))
+	if err != nil {
+		return err
+	}
+
+	if parent := e.push(); parent != nil {
+		if parent.children == 0 {
+			e.lines(depth-1, []string{"children:"})
+		}
+		parent.children++
+	}
+	e.started = append(e.started, false)
+	e.lines(depth, head)
+	return nil
+}
+
+// Leave implements tree.Visitor
+func (e *yamlEncoder) Leave(node *tree.Node, depth int) error {
+	if e.skip(depth) {
+		return nil
+	}
+	if node.Type == tree.Directory {
+		totals, err := yamlLines(totalFields(node, e.cfg))
+		if err != nil {
+			return err
+		}
+		e.lines(depth, totals)
+	}
+	if !e.started[depth] {
+		e.lines(depth, []string{"{}"})
+	}
+	e.pop()
+	e.started = e.started[:depth]
+	return nil
+}
+
+// Close implements Encoder
+func (e *yamlEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// xmlEncoder streams a tree as an XML document of nested <node>
+// elements, laid out like the output of formatXML
+type xmlEncoder struct {
+	streamEncoder
+}
+
+// newXMLEncoder creates an XML Encoder
+func newXMLEncoder(w io.Writer, cfg *configs.FormatCfg) Encoder {
+	return &xmlEncoder{streamEncoder: newStreamEncoder(w, cfg)}
+}
+
+// newline returns the line break and indentation of a nesting level
+func (e *xmlEncoder) newline(level int) string {
+	return "\n" + strings.Repeat("  ", level)
+}
+
+// fields marshals v as a <node> element at depth and returns its
+// content without the enclosing tags
+func (e *xmlEncoder) fields(v *filteredNode, depth int) (string, error) {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	enc.Indent(strings.Repeat("  ", 2*depth), "  ")
+	if err := enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "node"}}); err != nil {
+		return "", err
+	}
+	if err := enc.Flush(); err != nil {
+		return "", err
+	}
+
+	// Children are written by the encoder, drop the empty wrapper
+	content := strings.TrimSpace(buf.String())
+	content = strings.Replace(content, "\n"+strings.Repeat("  ", 2*depth+1)+"<children></children>", "", 1)
+	content = strings.TrimPrefix(content, "<node>")
+	content = strings.TrimSuffix(content, "</node>")
+	return strings.TrimRight(content, " \n"), nil
+}
+
+// Enter implements tree.Visitor
+func (e *xmlEncoder) Enter(node *tree.Node, depth int, last bool) error {
+	if e.skip(depth) {
+		return nil
+	}
+	head, err := e.fields(headFields(node, e.cfg), depth)
+	if err != nil {
+		return err
+	}
+
+	if parent := e.push(); parent != nil {
+		if parent.children == 0 {
+			e.write(e.newline(2*depth-1) + "<children>")
+		}
+		parent.children++
+		e.write(e.newline(2 * depth))
+	}
+	e.write("<node>" + head)
+	e.stack[len(e.stack)-1].fields = head != ""
+	return nil
+}
+
+// Leave implements tree.Visitor
+func (e *xmlEncoder) Leave(node *tree.Node, depth int) error {
+	if e.skip(depth) {
+		return nil
+	}
+	frame := e.pop()
+	if frame.children > 0 {
+		e.write(e.newline(2*depth+1) + "</children>")
+	}
+	if node.Type == tree.Directory {
+		totals, err := e.fields(totalFields(node, e.cfg), depth)
+		if err != nil {
+			return err
+		}
+		if totals != "" {
+			e.write(totals)
+			frame.fields = true
+		}
+	}
+	if frame.fields || frame.children > 0 {
+		e.write(e.newline(2 * depth))
+	}
+	e.write("</node>")
+	return nil
+}
+
+// Close implements Encoder
+func (e *xmlEncoder) Close() error {
+	e.write("\n")
+	return e.w.Flush()
+}
+
+// txtEncoder streams a tree as text in the style selected by the config.
+// Directory sizes are not known when their line is written and are left
+// out.
+type txtEncoder struct {
+	streamEncoder
+	colors      *lsColors
+	last        []bool // whether each open node is the last child of its parent
+	dirs, files int
+}
+
+// newTXTEncoder creates a TXT Encoder
+func newTXTEncoder(w io.Writer, cfg *configs.FormatCfg) Encoder {
+	return &txtEncoder{streamEncoder: newStreamEncoder(w, cfg), colors: newColors(cfg)}
+}
+
+// Enter implements tree.Visitor
+func (e *txtEncoder) Enter(node *tree.Node, depth int, last bool) error {
+	if e.skip(depth) {
+		return nil
+	}
+	e.last = append(e.last[:depth], last)
+	if depth == 0 {
+		e.write(txtLine(node, nil, e.cfg, e.colors) + "\n")
+		return nil
+	}
+
+	if node.Type == tree.Directory {
+		e.dirs++
+	} else {
+		e.files++
+	}
+
+	line := strings.Repeat("  ", depth)
+	if connectors, boxed := txtStyles[e.cfg.Style]; boxed {
+		line = ""
+		for _, ancestorLast := range e.last[1:depth] {
+			if ancestorLast {
+				line += connectors.space
+			} else {
+				line += connectors.vertical
+			}
+		}
+		if last {
+			line += connectors.last
+		} else {
+			line += connectors.branch
+		}
+	}
+	e.write(line + txtLine(node, nil, e.cfg, e.colors) + "\n")
+	return nil
+}
+
+// Leave implements tree.Visitor
+func (e *txtEncoder) Leave(node *tree.Node, depth int) error {
+	return nil
+}
+
+// Close implements Encoder
+func (e *txtEncoder) Close() error {
+	e.write(fmt.Sprintf("\n%s, %s\n", plural(e.dirs, "directory", "directories"), plural(e.files, "file", "files")))
+	return e.w.Flush()
+}
+
+// ndjsonEncoder writes one JSON object per line for every node, without
+// children. Like du, a directory is written after its contents, once its
+// totals are known.
+type ndjsonEncoder struct {
+	streamEncoder
+}
+
+// newNDJSONEncoder creates an NDJSON Encoder
+func newNDJSONEncoder(w io.Writer, cfg *configs.FormatCfg) Encoder {
+	return &ndjsonEncoder{streamEncoder: newStreamEncoder(w, cfg)}
+}
+
+// Enter implements tree.Visitor
+func (e *ndjsonEncoder) Enter(node *tree.Node, depth int, last bool) error {
+	return nil
+}
+
+// Leave implements tree.Visitor
+func (e *ndjsonEncoder) Leave(node *tree.Node, depth int) error {
+	if e.skip(depth) {
+		return nil
+	}
+	data, err := json.Marshal(fullFields(node, e.cfg))
+	if err != nil {
+		return err
+	}
+	e.write(string(data) + "\n")
+	return nil
+}
+
+// Close implements Encoder
+func (e *ndjsonEncoder) Close() error {
+	return e.w.Flush()
+}
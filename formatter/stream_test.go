@@ -0,0 +1,262 @@
+package formatter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+	"gopkg.in/yaml.v2"
+)
+
+// streamTree is the tree used by the streaming tests
+func streamTree() *tree.Node {
+	return &tree.Node{
+		Name: "root", Path: "root", Type: tree.Directory, Size: 3, FileCount: 2, DirCount: 2, MaxDepth: 2,
+		Children: []*tree.Node{
+			{Name: "a.txt", Path: "root/a.txt", Type: tree.File, Size: 1},
+			{
+				Name: "sub", Path: "root/sub", Type: tree.Directory, Size: 2, FileCount: 1, DirCount: 1, MaxDepth: 2,
+				Children: []*tree.Node{
+					{Name: "empty", Path: "root/sub/empty", Type: tree.Directory},
+					{Name: "b.txt", Path: "root/sub/b.txt", Type: tree.File, Size: 2, IsHidden: true},
+				},
+			},
+		},
+	}
+}
+
+// encode writes node through the encoder for cfg
+func encode(t *testing.T, node *tree.Node, cfg *configs.FormatCfg) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc, err := NewEncoder(&buf, cfg)
+	if err != nil {
+		t.Fatalf("NewEncoder returned error: %v", err)
+	}
+	if err := encodeTree(enc, node); err != nil {
+		t.Fatalf("Encoding returned error: %v", err)
+	}
+	return buf.String()
+}
+
+// TestEncoderMatchesFormat tests that streamed documents decode to the
+// same tree as formatted ones
+func TestEncoderMatchesFormat(t *testing.T) {
+	decoders := map[configs.OutputFormat]func([]byte) (interface{}, error){
+		configs.JSON: func(data []byte) (interface{}, error) {
+			var v interface{}
+			err := json.Unmarshal(data, &v)
+			return v, err
+		},
+		configs.YAML: func(data []byte) (interface{}, error) {
+			var v interface{}
+			err := yaml.Unmarshal(data, &v)
+			return v, err
+		},
+		configs.XML: func(data []byte) (interface{}, error) {
+			var v filteredNode
+			err := xml.Unmarshal(data, &v)
+			return &v, err
+		},
+	}
+
+	configsByName := map[string]configs.FormatCfg{
+		"Compact":  {SizeFormat: configs.IECSize},
+		"Indented": {Indent: 2, SizeFormat: configs.IECSize},
+		"Excluded": {Indent: 4, ExcludeNodeFields: []string{"path", "size"}},
+		"Children": {ExcludeNodeFields: []string{"children"}},
+		"Empty":    {ExcludeNodeFields: []string{"name", "path", "type", "size", "is_hidden", "file_count", "dir_count", "max_depth"}},
+	}
+
+	for format, decode := range decoders {
+		for name, cfg := range configsByName {
+			t.Run(string(format)+"/"+name, func(t *testing.T) {
+				cfg.Type = format
+				formatted, err := Format(streamTree(), &cfg)
+				if err != nil {
+					t.Fatalf("Format returned error: %v", err)
+				}
+				streamed := encode(t, streamTree(), &cfg)
+
+				expected, err := decode(formatted)
+				if err != nil {
+					t.Fatalf("Decoding formatted output: %v", err)
+				}
+				result, err := decode([]byte(streamed))
+				if err != nil {
+					t.Fatalf("Decoding streamed output: %v\n%s", err, streamed)
+				}
+				if !reflect.DeepEqual(result, expected) {
+					t.Errorf("Streamed\n%s\nwant the same tree as\n%s", streamed, formatted)
+				}
+			})
+		}
+	}
+}
+
+// TestJSONEncoderLayout tests the layout of indented JSON
+func TestJSONEncoderLayout(t *testing.T) {
+	root := &tree.Node{Name: "root", Type: tree.Directory, Size: 1, Children: []*tree.Node{
+		{Name: "a", Type: tree.File, Size: 1},
+	}}
+
+	result := encode(t, root, &configs.FormatCfg{Type: configs.JSON, Indent: 2})
+	expected := "{\n" +
+		"  \"name\": \"root\",\n" +
+		"  \"type\": \"directory\",\n" +
+		"  \"children\": [\n" +
+		"    {\n" +
+		"      \"name\": \"a\",\n" +
+		"      \"type\": \"file\",\n" +
+		"      \"size\": 1\n" +
+		"    }\n" +
+		"  ],\n" +
+		"  \"size\": 1\n" +
+		"}\n"
+	if result != expected {
+		t.Errorf("Encoded\n%s\nwant\n%s", result, expected)
+	}
+}
+
+// TestTXTEncoder tests streamed text, whose directories have no sizes
+// while they are walked
+func TestTXTEncoder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"cmd/main.go":    {Data: []byte("package main")},
+		"docs/guide.md":  {Data: []byte("# Guide")},
+		"docs/api/x.txt": {Data: []byte("x")},
+		"go.mod":         {Data: []byte("module x")},
+	}
+
+	tests := []struct {
+		style    configs.TXTStyle
+		expected string
+	}{
+		{
+			style: configs.UnicodeStyle,
+			expected: ".\n" +
+				"├── cmd\n" +
+				"│   └── main.go (12 bytes)\n" +
+				"├── docs\n" +
+				"│   ├── api\n" +
+				"│   │   └── x.txt (1 bytes)\n" +
+				"│   └── guide.md (7 bytes)\n" +
+				"└── go.mod (8 bytes)\n" +
+				"\n3 directories, 4 files\n",
+		},
+		{
+			style: configs.PlainStyle,
+			expected: ".\n" +
+				"  cmd\n" +
+				"    main.go (12 bytes)\n" +
+				"  docs\n" +
+				"    api\n" +
+				"      x.txt (1 bytes)\n" +
+				"    guide.md (7 bytes)\n" +
+				"  go.mod (8 bytes)\n" +
+				"\n3 directories, 4 files\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &configs.FormatCfg{Type: configs.TXT, Style: tt.style, ExcludeNodeFields: []string{"is_hidden"}}
+			enc, err := NewEncoder(&buf, cfg)
+			if err != nil {
+				t.Fatalf("NewEncoder returned error: %v", err)
+			}
+			opts := tree.BuildOptions{MaxDepth: -1, IncludeFiles: true}
+			if err := tree.WalkTreeFS(context.Background(), fsys, opts, enc); err != nil {
+				t.Fatalf("WalkTreeFS returned error: %v", err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatalf("Close returned error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Encoded\n%s\nwant\n%s", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestNDJSON tests that nodes are written one per line, after their contents
+func TestNDJSON(t *testing.T) {
+	data, err := Format(streamTree(), &configs.FormatCfg{Type: configs.NDJSON, ExcludeNodeFields: []string{"name"}})
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var node tree.Node
+		if err := json.Unmarshal([]byte(line), &node); err != nil {
+			t.Fatalf("Invalid line %q: %v", line, err)
+		}
+		if node.Name != "" {
+			t.Errorf("Excluded name written in %q", line)
+		}
+		if node.Path == "root/sub" && node.Size != 2 {
+			t.Errorf("Directory written without totals: %q", line)
+		}
+		paths = append(paths, node.Path)
+	}
+
+	expected := []string{"root/a.txt", "root/sub/empty", "root/sub/b.txt", "root/sub", "root"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Lines in order %v, want %v", paths, expected)
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{ err error }
+
+// Write implements io.Writer
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+// TestEncoderWriteError tests that Close reports the first write error
+func TestEncoderWriteError(t *testing.T) {
+	fail := errors.New("disk full")
+	for _, format := range []configs.OutputFormat{configs.JSON, configs.YAML, configs.XML, configs.TXT, configs.NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			enc, err := NewEncoder(failingWriter{fail}, &configs.FormatCfg{Type: format})
+			if err != nil {
+				t.Fatalf("NewEncoder returned error: %v", err)
+			}
+			if err := encodeTree(enc, streamTree()); !errors.Is(err, fail) {
+				t.Errorf("Encoding error = %v, want %v", err, fail)
+			}
+		})
+	}
+}
+
+// TestNewEncoderErrors tests formats and options that cannot be streamed
+func TestNewEncoderErrors(t *testing.T) {
+	const name configs.OutputFormat = "names-stream-test"
+	registerForTest(t, name, namesFormatter{})
+
+	tests := []struct {
+		name string
+		cfg  configs.FormatCfg
+	}{
+		{"Unknown", configs.FormatCfg{Type: "unknown"}},
+		{"NotStreaming", configs.FormatCfg{Type: name}},
+		{"Percent", configs.FormatCfg{Type: configs.JSON, Percent: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEncoder(&bytes.Buffer{}, &tt.cfg); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
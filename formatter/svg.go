@@ -0,0 +1,333 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// SVG layout constants, in pixels
+const (
+	svgFontSize  = 11.0
+	svgCharWidth = svgFontSize * 0.6 // Average width of a character, for fitting labels
+	svgHeader    = 16.0              // Height of the label strip of a treemap directory
+	svgPadding   = 2.0               // Gap between a treemap directory and its children
+	svgMinimum   = 0.5               // Smallest width or height still drawn
+)
+
+// box is a rectangle of a treemap
+type box struct {
+	x, y, w, h float64
+}
+
+// svgChart writes the shapes of a chart of node sizes
+type svgChart struct {
+	result  strings.Builder
+	cfg     *configs.FormatCfg
+	byDepth bool
+}
+
+// formatSVG formats the tree as an SVG treemap or sunburst of node sizes,
+// as selected by the "chart" option. Directories are sized by their
+// aggregate size, and every shape has a tooltip with its path and size.
+func formatSVG(node *tree.Node, cfg *configs.FormatCfg) ([]byte, error) {
+	width, err := svgDimension(cfg, "width")
+	if err != nil {
+		return nil, err
+	}
+	height, err := svgDimension(cfg, "height")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &svgChart{cfg: cfg, byDepth: cfg.Option("color") == "depth"}
+	fmt.Fprintf(&c.result, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(&c.result, "<style>text{font:%gpx sans-serif;fill:#1f2328;pointer-events:none}path,rect{stroke:#fff;stroke-width:0.5}</style>\n", svgFontSize)
+
+	// Tooltips show paths from the root's name when paths are excluded
+	rel := node.Name
+	if rel == "" || contains(cfg.ExcludeNodeFields, "name") {
+		rel = "."
+	}
+	switch chart := configs.SVGChart(cfg.Option("chart")); chart {
+	case configs.SVGTreemap:
+		c.treemap(node, rel, box{0, 0, float64(width), float64(height)}, 0)
+	case configs.SVGSunburst:
+		levels := treeDepth(node)
+		cx, cy := float64(width)/2, float64(height)/2
+		ring := (math.Min(cx, cy) - svgPadding) / float64(levels+1)
+		c.sunburst(node, rel, cx, cy, ring, 0, 2*math.Pi, 0)
+	default:
+		return nil, fmt.Errorf("unsupported svg chart: %s", chart)
+	}
+
+	c.result.WriteString("</svg>\n")
+	return []byte(c.result.String()), nil
+}
+
+// svgDimension returns the positive size in pixels given by an option
+func svgDimension(cfg *configs.FormatCfg, name string) (int, error) {
+	value, err := strconv.Atoi(cfg.Option(name))
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid %s option: %q", name, cfg.Option(name))
+	}
+	return value, nil
+}
+
+// treemap draws node into b and lays out its children inside it
+func (c *svgChart) treemap(node *tree.Node, rel string, b box, depth int) {
+	fmt.Fprintf(&c.result, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s">`, b.x, b.y, b.w, b.h, c.color(node, depth))
+	c.writeTitle(node, rel)
+	c.result.WriteString("</rect>\n")
+
+	children, sizes := c.sized(node)
+	if len(children) == 0 {
+		c.writeLabel(node, b.x+3, b.y+svgFontSize+1, b.w-6, b.h)
+		return
+	}
+
+	// Directories keep a strip for their label when there is room for it
+	header := svgPadding
+	if b.h >= 3*svgHeader {
+		header = svgHeader
+		c.writeLabel(node, b.x+3, b.y+svgFontSize+1, b.w-6, svgHeader)
+	}
+	inner := box{b.x + svgPadding, b.y + header, b.w - 2*svgPadding, b.h - header - svgPadding}
+	if inner.w < svgMinimum || inner.h < svgMinimum {
+		return
+	}
+	for i, child := range squarify(sizes, inner) {
+		if child.w >= svgMinimum && child.h >= svgMinimum {
+			c.treemap(children[i], path.Join(rel, children[i].Name), child, depth+1)
+		}
+	}
+}
+
+// sunburst draws node as the arc from start to end of the ring at depth,
+// or as the center for the root, and its children in the rings outside
+func (c *svgChart) sunburst(node *tree.Node, rel string, cx, cy, ring, start, end float64, depth int) {
+	inner, outer := float64(depth)*ring, float64(depth+1)*ring
+	if depth == 0 {
+		fmt.Fprintf(&c.result, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s">`, cx, cy, outer, c.color(node, depth))
+	} else {
+		fmt.Fprintf(&c.result, `<path d="%s" fill="%s">`, arcPath(cx, cy, inner, outer, start, end), c.color(node, depth))
+	}
+	c.writeTitle(node, rel)
+	if depth == 0 {
+		c.result.WriteString("</circle>\n")
+		c.writeCenteredLabel(node, cx, cy, 2*outer)
+	} else {
+		c.result.WriteString("</path>\n")
+		c.writeRadialLabel(node, cx, cy, inner, outer, start, end)
+	}
+
+	children, sizes := c.sized(node)
+	var total float64
+	for _, size := range sizes {
+		total += size
+	}
+	for i, child := range children {
+		sweep := (end - start) * sizes[i] / total
+		// Arcs too thin to see are left out, with their descendants
+		if sweep*(outer+ring) >= svgMinimum {
+			c.sunburst(child, path.Join(rel, child.Name), cx, cy, ring, start, start+sweep, depth+1)
+		}
+		start += sweep
+	}
+}
+
+// sized returns the children of node with a size, largest first, and
+// their sizes
+func (c *svgChart) sized(node *tree.Node) ([]*tree.Node, []float64) {
+	var children []*tree.Node
+	for _, child := range node.Children {
+		if size, _ := nodeSize(child, c.cfg); size > 0 {
+			children = append(children, child)
+		}
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		a, _ := nodeSize(children[i], c.cfg)
+		b, _ := nodeSize(children[j], c.cfg)
+		return a > b
+	})
+
+	sizes := make([]float64, len(children))
+	for i, child := range children {
+		size, _ := nodeSize(child, c.cfg)
+		sizes[i] = float64(size)
+	}
+	return children, sizes
+}
+
+// squarify lays out rectangles with areas proportional to values, which
+// must be positive and sorted in decreasing order, filling b. Rows are
+// grown along the shorter side while that improves their worst aspect
+// ratio, as in the algorithm of Bruls, Huizing and van Wijk.
+func squarify(values []float64, b box) []box {
+	var total float64
+	for _, value := range values {
+		total += value
+	}
+	areas := make([]float64, len(values))
+	for i, value := range values {
+		areas[i] = value * b.w * b.h / total
+	}
+
+	result := make([]box, len(values))
+	for i := 0; i < len(areas); {
+		side := math.Min(b.w, b.h)
+		j := i + 1
+		for j < len(areas) && worstRatio(areas[i:j+1], side) <= worstRatio(areas[i:j], side) {
+			j++
+		}
+
+		var row float64
+		for _, area := range areas[i:j] {
+			row += area
+		}
+		if b.w >= b.h {
+			// A column along the left side
+			width, y := row/b.h, b.y
+			for k := i; k < j; k++ {
+				result[k] = box{b.x, y, width, areas[k] / width}
+				y += areas[k] / width
+			}
+			b.x, b.w = b.x+width, b.w-width
+		} else {
+			// A row along the top
+			height, x := row/b.w, b.x
+			for k := i; k < j; k++ {
+				result[k] = box{x, b.y, areas[k] / height, height}
+				x += areas[k] / height
+			}
+			b.y, b.h = b.y+height, b.h-height
+		}
+		i = j
+	}
+	return result
+}
+
+// worstRatio returns the largest aspect ratio of a row of areas laid out
+// along a side
+func worstRatio(row []float64, side float64) float64 {
+	var sum float64
+	largest, smallest := row[0], row[0]
+	for _, area := range row {
+		sum += area
+		largest = math.Max(largest, area)
+		smallest = math.Min(smallest, area)
+	}
+	return math.Max(side*side*largest/(sum*sum), sum*sum/(side*side*smallest))
+}
+
+// arcPath returns the path of the ring segment between two radii and two
+// angles, measured clockwise from the top. Full circles are drawn as two
+// halves, since a single arc cannot end where it starts.
+func arcPath(cx, cy, inner, outer, start, end float64) string {
+	if end-start >= 2*math.Pi-1e-9 {
+		mid := start + math.Pi
+		return arcPath(cx, cy, inner, outer, start, mid) + " " + arcPath(cx, cy, inner, outer, mid, end)
+	}
+	point := func(r, angle float64) string {
+		return fmt.Sprintf("%.1f %.1f", cx+r*math.Sin(angle), cy-r*math.Cos(angle))
+	}
+	large := 0
+	if end-start > math.Pi {
+		large = 1
+	}
+	return fmt.Sprintf("M%s A%.1f %.1f 0 %d 1 %s L%s A%.1f %.1f 0 %d 0 %s Z",
+		point(outer, start), outer, outer, large, point(outer, end),
+		point(inner, end), inner, inner, large, point(inner, start))
+}
+
+// color returns the fill of a node: files by extension and directories in
+// grays, or every node by depth
+func (c *svgChart) color(node *tree.Node, depth int) string {
+	if c.byDepth {
+		return fmt.Sprintf("hsl(%d,60%%,%d%%)", (200+depth*40)%360, 55+depth%3*8)
+	}
+	if node.Type == tree.Directory {
+		return fmt.Sprintf("hsl(0,0%%,%d%%)", 92-depth%4*5)
+	}
+	ext := strings.ToLower(path.Ext(node.Name))
+	if ext == "" {
+		return "hsl(0,0%,70%)"
+	}
+	h := fnv.New32a()
+	h.Write([]byte(ext))
+	return fmt.Sprintf("hsl(%d,65%%,60%%)", h.Sum32()%360)
+}
+
+// writeTitle writes the tooltip of a node: its path and size
+func (c *svgChart) writeTitle(node *tree.Node, rel string) {
+	name := node.Path
+	if name == "" || contains(c.cfg.ExcludeNodeFields, "path") {
+		name = rel
+	}
+	size, _ := nodeSize(node, c.cfg)
+	c.result.WriteString("<title>")
+	xml.EscapeText(&c.result, []byte(name+"\n"+FormatSize(size, c.cfg.SizeFormat)))
+	c.result.WriteString("</title>")
+}
+
+// writeLabel writes the name of a node at x and y if it fits the width
+// and height left for it
+func (c *svgChart) writeLabel(node *tree.Node, x, y, width, height float64) {
+	if contains(c.cfg.ExcludeNodeFields, "name") || height < svgFontSize+2 || labelWidth(node.Name) > width {
+		return
+	}
+	fmt.Fprintf(&c.result, `<text x="%.1f" y="%.1f">`, x, y)
+	xml.EscapeText(&c.result, []byte(node.Name))
+	c.result.WriteString("</text>\n")
+}
+
+// writeCenteredLabel writes the name of the sunburst root in its center
+func (c *svgChart) writeCenteredLabel(node *tree.Node, cx, cy, width float64) {
+	if contains(c.cfg.ExcludeNodeFields, "name") || labelWidth(node.Name) > width {
+		return
+	}
+	fmt.Fprintf(&c.result, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="middle">`, cx, cy)
+	xml.EscapeText(&c.result, []byte(node.Name))
+	c.result.WriteString("</text>\n")
+}
+
+// writeRadialLabel writes the name of a sunburst arc along its middle
+// radius, if the ring is long enough for it and the arc wide enough
+func (c *svgChart) writeRadialLabel(node *tree.Node, cx, cy, inner, outer, start, end float64) {
+	mid, radius := (start+end)/2, (inner+outer)/2
+	if contains(c.cfg.ExcludeNodeFields, "name") || (end-start)*radius < svgFontSize+2 || labelWidth(node.Name) > outer-inner-4 {
+		return
+	}
+	// Text runs outwards on the right half and inwards on the left one,
+	// so it is never upside down
+	rotation := mid*180/math.Pi - 90
+	if mid > math.Pi {
+		rotation += 180
+	}
+	x, y := cx+radius*math.Sin(mid), cy-radius*math.Cos(mid)
+	fmt.Fprintf(&c.result, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="middle" transform="rotate(%.1f %.1f %.1f)">`, x, y, rotation, x, y)
+	xml.EscapeText(&c.result, []byte(node.Name))
+	c.result.WriteString("</text>\n")
+}
+
+// labelWidth estimates the width of a label in pixels
+func labelWidth(name string) float64 {
+	return float64(len([]rune(name))) * svgCharWidth
+}
+
+// treeDepth returns the number of levels below node
+func treeDepth(node *tree.Node) int {
+	depth := 0
+	for _, child := range node.Children {
+		depth = max(depth, treeDepth(child)+1)
+	}
+	return depth
+}
@@ -0,0 +1,195 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Maxim-Ba/dir-tree/configs"
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// TestSquarify tests that treemap rectangles tile their box in proportion
+// to their values
+func TestSquarify(t *testing.T) {
+	values := []float64{6, 6, 4, 3, 2, 2, 1}
+	bounds := box{10, 20, 600, 400}
+	boxes := squarify(values, bounds)
+
+	var area float64
+	for i, b := range boxes {
+		expected := values[i] / 24 * bounds.w * bounds.h
+		if math.Abs(b.w*b.h-expected) > 1e-6 {
+			t.Errorf("Box %d area = %v, want %v", i, b.w*b.h, expected)
+		}
+		if b.x < bounds.x-1e-6 || b.y < bounds.y-1e-6 || b.x+b.w > bounds.x+bounds.w+1e-6 || b.y+b.h > bounds.y+bounds.h+1e-6 {
+			t.Errorf("Box %d = %+v is outside %+v", i, b, bounds)
+		}
+		if ratio := math.Max(b.w/b.h, b.h/b.w); ratio > 3 {
+			t.Errorf("Box %d = %+v has aspect ratio %.1f", i, b, ratio)
+		}
+		area += b.w * b.h
+	}
+	if math.Abs(area-bounds.w*bounds.h) > 1e-6 {
+		t.Errorf("Boxes cover %v, want %v", area, bounds.w*bounds.h)
+	}
+}
+
+// TestFormatSVG tests the shapes, labels and tooltips of both charts
+func TestFormatSVG(t *testing.T) {
+	root := &tree.Node{
+		Name: "project",
+		Type: tree.Directory,
+		Size: 3000,
+		Children: []*tree.Node{
+			{
+				Name: "src",
+				Type: tree.Directory,
+				Size: 2000,
+				Children: []*tree.Node{
+					{Name: "main.go", Type: tree.File, Size: 1500},
+					{Name: "a&b.go", Type: tree.File, Size: 500},
+				},
+			},
+			{Name: "README", Type: tree.File, Size: 1000},
+			{Name: "empty.txt", Type: tree.File},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		options       map[string]string
+		excludeFields []string
+		shapes        int
+		labels        []string
+		tooltip       string
+	}{
+		{
+			name:    "Treemap",
+			shapes:  5,
+			labels:  []string{"project", "src", "main.go", "a&b.go", "README"},
+			tooltip: "project/src/main.go\n1500 bytes",
+		},
+		{
+			name:    "Sunburst",
+			options: map[string]string{"chart": "sunburst", "color": "depth"},
+			shapes:  5,
+			labels:  []string{"project", "src", "main.go", "a&b.go", "README"},
+			tooltip: "project/src/main.go\n1500 bytes",
+		},
+		{
+			name:          "Treemap without names",
+			options:       map[string]string{"width": "300", "height": "200"},
+			excludeFields: []string{"name"},
+			shapes:        5,
+			tooltip:       "src/main.go\n1500 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &configs.FormatCfg{Type: configs.SVG, ExcludeNodeFields: tt.excludeFields, Options: tt.options}
+			result, err := Format(root, cfg)
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+
+			var shapes int
+			var labels, titles []string
+			decoder := xml.NewDecoder(strings.NewReader(string(result)))
+			for {
+				token, err := decoder.Token()
+				if err != nil {
+					break
+				}
+				start, ok := token.(xml.StartElement)
+				if !ok {
+					continue
+				}
+				switch start.Name.Local {
+				case "rect", "path", "circle":
+					shapes++
+				case "text", "title":
+					var text string
+					if err := decoder.DecodeElement(&text, &start); err != nil {
+						t.Fatalf("Invalid SVG: %v", err)
+					}
+					if start.Name.Local == "text" {
+						labels = append(labels, text)
+					} else {
+						titles = append(titles, text)
+					}
+				}
+			}
+
+			if shapes != tt.shapes || len(titles) != tt.shapes {
+				t.Errorf("Got %d shapes and %d tooltips, want %d", shapes, len(titles), tt.shapes)
+			}
+			if strings.Join(labels, ",") != strings.Join(tt.labels, ",") {
+				t.Errorf("Labels = %v, want %v", labels, tt.labels)
+			}
+			if len(titles) > 2 && titles[2] != tt.tooltip {
+				t.Errorf("Tooltip = %q, want %q", titles[2], tt.tooltip)
+			}
+		})
+	}
+
+	invalid := []map[string]string{
+		{"chart": "pie"},
+		{"width": "0"},
+		{"height": "tall"},
+	}
+	for _, options := range invalid {
+		cfg := &configs.FormatCfg{Type: configs.SVG, Options: options}
+		if _, err := Format(root, cfg); err == nil {
+			t.Errorf("Expected error for options %v", options)
+		}
+	}
+}
+
+// TestFormatSVGMaxDepth tests that directories at the maximum depth are
+// drawn with the area of their contents
+func TestFormatSVGMaxDepth(t *testing.T) {
+	fsys := fstest.MapFS{
+		"small.txt":        {Data: make([]byte, 100)},
+		"src/main.go":      {Data: make([]byte, 100)},
+		"src/pkg/lib.go":   {Data: make([]byte, 200)},
+		"docs/img/big.png": {Data: make([]byte, 600)},
+	}
+	root, err := tree.BuildTreeFS(fsys, tree.BuildOptions{MaxDepth: 1, IncludeFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &configs.FormatCfg{Type: configs.SVG, Options: map[string]string{"width": "400", "height": "300"}}
+	result, err := Format(root, cfg)
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+
+	var svg struct {
+		Rects []struct {
+			Width  float64 `xml:"width,attr"`
+			Height float64 `xml:"height,attr"`
+			Title  string  `xml:"title"`
+		} `xml:"rect"`
+	}
+	if err := xml.Unmarshal(result, &svg); err != nil {
+		t.Fatalf("Invalid SVG: %v", err)
+	}
+
+	areas := map[string]float64{}
+	for _, rect := range svg.Rects {
+		name, _, _ := strings.Cut(rect.Title, "\n")
+		areas[name] = rect.Width * rect.Height
+	}
+	if len(svg.Rects) != 4 {
+		t.Fatalf("Got %d rectangles, want the root and its 3 children: %v", len(svg.Rects), areas)
+	}
+	docs, src, small := areas["docs"], areas["src"], areas["small.txt"]
+	if docs <= 0 || math.Abs(docs/src-2) > 0.01 || math.Abs(src/small-3) > 0.01 {
+		t.Errorf("Areas = %v, want docs, src and small.txt in proportion 6:3:1", areas)
+	}
+}
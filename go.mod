@@ -2,10 +2,12 @@ module github.com/Maxim-Ba/dir-tree
 
 go 1.23.4
 
-require github.com/spf13/viper v1.21.0
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/spf13/viper v1.21.0
+)
 
 require (
-	github.com/fsnotify/fsnotify v1.9.0 // indirect
 	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
 	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
 	github.com/sagikazarmark/locafero v0.11.0 // indirect
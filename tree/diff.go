@@ -0,0 +1,161 @@
+package tree
+
+import (
+	"path"
+	"sort"
+)
+
+// ChangeKind classifies a difference found by Diff
+type ChangeKind string
+
+const (
+	Added       ChangeKind = "added"
+	Removed     ChangeKind = "removed"
+	Modified    ChangeKind = "modified"
+	TypeChanged ChangeKind = "type_changed"
+)
+
+// Change is a difference between two trees
+type Change struct {
+	Kind   ChangeKind `json:"kind"`
+	Path   string     `json:"path"`             // Slash separated path relative to the roots, "." for the roots
+	Fields []string   `json:"fields,omitempty"` // What was modified: "size", "mod_time", "hash" or "link_target"
+	Old    *Node      `json:"old,omitempty"`    // Node in the old tree, nil if added
+	New    *Node      `json:"new,omitempty"`    // Node in the new tree, nil if removed
+}
+
+// Diff compares the old tree a with the new tree b. Nodes are matched by
+// their path relative to the roots, so trees scanned from different
+// directories or loaded from snapshots can be compared. Added and
+// removed directories are reported once, without their contents, and
+// nodes whose type changed are not descended into.
+//
+// Files are modified when their size, modification time or content hash
+// differ, and symbolic links when their link target (or, without
+// metadata, their size) differs. Times, hashes and link targets are only
+// compared when both trees have them, see BuildOptions.CollectMetadata
+// and BuildOptions.Hash. Types are only compared when both nodes have
+// one. Changes are ordered by path.
+func Diff(a, b *Node) []Change {
+	return DiffWithOptions(a, b, DiffOptions{})
+}
+
+// DiffOptions controls what DiffWithOptions compares
+type DiffOptions struct {
+	// Skip lists fields that are not compared, "type" or "size", e.g.
+	// because a snapshot was written without them
+	Skip []string
+}
+
+// DiffWithOptions is like Diff, without comparing the fields in
+// opts.Skip. Sizes are zero in a snapshot written without them, so they
+// cannot be told apart from empty files and must be skipped explicitly.
+func DiffWithOptions(a, b *Node, opts DiffOptions) []Change {
+	var changes []Change
+	diffNodes(&changes, ".", a, b, opts)
+	return changes
+}
+
+// skips reports whether field is not compared
+func (opts DiffOptions) skips(field string) bool {
+	for _, skipped := range opts.Skip {
+		if skipped == field {
+			return true
+		}
+	}
+	return false
+}
+
+// diffNodes appends the changes between the matching nodes a and b
+func diffNodes(changes *[]Change, rel string, a, b *Node, opts DiffOptions) {
+	typ := a.Type
+	switch {
+	case a.Type == "" || b.Type == "" || opts.skips("type"):
+		typ = commonType(a, b)
+	case a.Type != b.Type:
+		*changes = append(*changes, Change{Kind: TypeChanged, Path: rel, Old: a, New: b})
+		return
+	}
+	if typ != Directory {
+		if fields := modifiedFields(typ, a, b, opts); len(fields) > 0 {
+			*changes = append(*changes, Change{Kind: Modified, Path: rel, Fields: fields, Old: a, New: b})
+		}
+		return
+	}
+
+	old, current := childrenByName(a), childrenByName(b)
+	names := make([]string, 0, len(old)+len(current))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		childRel := path.Join(rel, name)
+		oldChild, newChild := old[name], current[name]
+		switch {
+		case newChild == nil:
+			*changes = append(*changes, Change{Kind: Removed, Path: childRel, Old: oldChild})
+		case oldChild == nil:
+			*changes = append(*changes, Change{Kind: Added, Path: childRel, New: newChild})
+		default:
+			diffNodes(changes, childRel, oldChild, newChild, opts)
+		}
+	}
+}
+
+// childrenByName indexes the children of a directory by name
+func childrenByName(node *Node) map[string]*Node {
+	children := make(map[string]*Node, len(node.Children))
+	for _, child := range node.Children {
+		children[child.Name] = child
+	}
+	return children
+}
+
+// commonType returns the type of two nodes whose types are not compared:
+// the type either of them has, or a directory if either has children
+func commonType(a, b *Node) FileType {
+	switch {
+	case a.Type == Directory || b.Type == Directory || len(a.Children) > 0 || len(b.Children) > 0:
+		return Directory
+	case a.Type != "":
+		return a.Type
+	default:
+		return b.Type
+	}
+}
+
+// modifiedFields returns the fields that differ between two files or two
+// symbolic links of type typ
+func modifiedFields(typ FileType, a, b *Node, opts DiffOptions) []string {
+	var fields []string
+	compareSize := !opts.skips("size")
+	if typ == Symlink {
+		switch {
+		case a.LinkTarget != "" && b.LinkTarget != "":
+			if a.LinkTarget != b.LinkTarget {
+				fields = append(fields, "link_target")
+			}
+		case compareSize && a.Size != b.Size:
+			fields = append(fields, "size")
+		}
+		return fields
+	}
+
+	if compareSize && a.Size != b.Size {
+		fields = append(fields, "size")
+	}
+	if a.ModTime != nil && b.ModTime != nil && !a.ModTime.Equal(*b.ModTime) {
+		fields = append(fields, "mod_time")
+	}
+	if a.Hash != "" && b.Hash != "" && a.Hash != b.Hash {
+		fields = append(fields, "hash")
+	}
+	return fields
+}
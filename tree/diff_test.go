@@ -0,0 +1,121 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// TestHashFiles tests that files are digested with the chosen algorithm
+func TestHashFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo.txt":     {Data: []byte("foo")},
+		"dir/bar.txt": {Data: []byte("bar")},
+	}
+
+	tests := []struct {
+		algorithm HashAlgorithm
+		expected  string
+	}{
+		{SHA256, "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"},
+		{SHA1, "sha1:0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33"},
+		{MD5, "md5:acbd18db4cc2f85cedef654fccc4a4d8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			root, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true, Hash: tt.algorithm})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if root.Children[1].Hash != tt.expected {
+				t.Errorf("Hash = %q, want %q", root.Children[1].Hash, tt.expected)
+			}
+			if root.Hash != "" || root.Children[0].Hash != "" || root.Children[0].Children[0].Hash == "" {
+				t.Errorf("Only files should be hashed: %+v", root)
+			}
+		})
+	}
+
+	if _, err := ParseHashAlgorithm("crc32"); err == nil {
+		t.Error("Expected error for unsupported algorithm")
+	}
+}
+
+// TestDiff tests the changes found between two trees
+func TestDiff(t *testing.T) {
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	old := &Node{Name: "old", Type: Directory, Children: []*Node{
+		{Name: "docs", Type: Directory, Children: []*Node{
+			{Name: "guide.md", Type: File, Size: 10},
+		}},
+		{Name: "gone", Type: Directory},
+		{Name: "main.go", Type: File, Size: 5, ModTime: &earlier, Hash: "sha256:aa"},
+		{Name: "same.go", Type: File, Size: 5, ModTime: &earlier, Hash: "sha256:bb"},
+		{Name: "swap", Type: File, Size: 1},
+		{Name: "link", Type: Symlink, Size: 2, LinkTarget: "aa"},
+	}}
+	current := &Node{Name: "new", Type: Directory, Children: []*Node{
+		{Name: "docs", Type: Directory, Children: []*Node{
+			{Name: "guide.md", Type: File, Size: 12},
+			{Name: "api.md", Type: File, Size: 3},
+		}},
+		{Name: "main.go", Type: File, Size: 5, ModTime: &later, Hash: "sha256:cc"},
+		{Name: "same.go", Type: File, Size: 5, Hash: "sha256:bb"},
+		{Name: "swap", Type: Directory},
+		{Name: "link", Type: Symlink, Size: 2, LinkTarget: "bb"},
+	}}
+
+	type change struct {
+		Kind   ChangeKind
+		Path   string
+		Fields []string
+	}
+	var result []change
+	for _, c := range Diff(old, current) {
+		result = append(result, change{c.Kind, c.Path, c.Fields})
+	}
+	expected := []change{
+		{Added, "docs/api.md", nil},
+		{Modified, "docs/guide.md", []string{"size"}},
+		{Removed, "gone", nil},
+		{Modified, "link", []string{"link_target"}},
+		{Modified, "main.go", []string{"mod_time", "hash"}},
+		{TypeChanged, "swap", nil},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Diff() = %+v, want %+v", result, expected)
+	}
+
+	if changes := Diff(old, old); len(changes) != 0 {
+		t.Errorf("Diff() of the same tree = %+v, want none", changes)
+	}
+
+	changes := Diff(&Node{Name: "a", Type: File}, &Node{Name: "b", Type: Directory})
+	if len(changes) != 1 || changes[0].Kind != TypeChanged || changes[0].Path != "." {
+		t.Errorf("Diff() of roots = %+v", changes)
+	}
+}
+
+// TestDiffWithOptions tests that skipped and missing fields are not compared
+func TestDiffWithOptions(t *testing.T) {
+	snapshot := &Node{Name: "snapshot", Children: []*Node{
+		{Name: "dir", Children: []*Node{{Name: "a.txt"}}},
+		{Name: "b.txt"},
+	}}
+	current := &Node{Name: "current", Type: Directory, Children: []*Node{
+		{Name: "dir", Type: Directory, Children: []*Node{{Name: "a.txt", Type: File, Size: 3}}},
+		{Name: "b.txt", Type: File, Size: 2},
+	}}
+
+	if changes := DiffWithOptions(snapshot, current, DiffOptions{Skip: []string{"size"}}); len(changes) != 0 {
+		t.Errorf("DiffWithOptions() = %+v, want none", changes)
+	}
+	changes := Diff(snapshot, current)
+	if len(changes) != 2 || changes[0].Kind != Modified || changes[1].Kind != Modified {
+		t.Errorf("Diff() = %+v, want both files modified", changes)
+	}
+}
@@ -0,0 +1,63 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorPolicy selects how BuildTree handles files and directories that
+// cannot be read
+type ErrorPolicy string
+
+const (
+	AbortOnError  ErrorPolicy = "abort"  // Stop the scan and return the error (default)
+	SkipOnError   ErrorPolicy = "skip"   // Leave unreadable paths out of the tree
+	RecordOnError ErrorPolicy = "record" // Keep unreadable paths with Node.Error set
+)
+
+// tolerant reports whether the scan continues past read errors
+func (p ErrorPolicy) tolerant() bool {
+	return p == SkipOnError || p == RecordOnError
+}
+
+// ScanError describes a path that could not be read during a scan
+type ScanError struct {
+	Path string // Path of the node that could not be read
+	Err  error  // Underlying file system error
+}
+
+// Error implements the error interface
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying file system error
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned together with a partially built tree when the
+// scan was cancelled before every directory could be read, or when paths
+// could not be read under the skip and record error policies
+type PartialError struct {
+	Err       error        // Reason the scan stopped, usually ctx.Err(); nil if it ran to the end
+	Unscanned []string     // Paths of directories whose contents were not read
+	Failures  []*ScanError // Paths that could not be read, sorted by path
+}
+
+// Error implements the error interface
+func (e *PartialError) Error() string {
+	var reasons []string
+	if e.Err != nil || len(e.Unscanned) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d directories not read: %v", len(e.Unscanned), e.Err))
+	}
+	if len(e.Failures) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d paths could not be read", len(e.Failures)))
+	}
+	return "scan incomplete, " + strings.Join(reasons, "; ")
+}
+
+// Unwrap returns the underlying cause, so errors.Is works with context errors
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
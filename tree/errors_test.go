@@ -0,0 +1,164 @@
+package tree
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// failingFS is a MapFS whose directories and entries can be made unreadable
+type failingFS struct {
+	fstest.MapFS
+	unreadable map[string]bool // names whose ReadDir or Info fails
+	vanished   map[string]bool // names whose Info reports fs.ErrNotExist
+}
+
+// ReadDir implements fs.ReadDirFS
+func (f failingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if f.unreadable[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	entries, err := f.MapFS.ReadDir(name)
+	for i, entry := range entries {
+		entry := failingEntry{DirEntry: entry}
+		switch full := path.Join(name, entry.Name()); {
+		case f.unreadable[full+"#info"]:
+			entry.err = fs.ErrPermission
+		case f.vanished[full]:
+			entry.err = fs.ErrNotExist
+		}
+		entries[i] = entry
+	}
+	return entries, err
+}
+
+// failingEntry is a directory entry whose Info can fail
+type failingEntry struct {
+	fs.DirEntry
+	err error
+}
+
+// Info implements fs.DirEntry
+func (e failingEntry) Info() (fs.FileInfo, error) {
+	if e.err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: e.Name(), Err: e.err}
+	}
+	return e.DirEntry.Info()
+}
+
+// TestBuildTreeErrorPolicy tests the abort, skip and record error policies
+func TestBuildTreeErrorPolicy(t *testing.T) {
+	fsys := failingFS{
+		MapFS: fstest.MapFS{
+			"ok/file.txt":      {Data: []byte("ok")},
+			"locked/file.txt":  {Data: []byte("secret")},
+			"stat/file.txt":    {Data: []byte("x")},
+			"stat/gone.txt":    {Data: []byte("x")},
+			"stat/visible.txt": {Data: []byte("x")},
+		},
+		unreadable: map[string]bool{
+			"locked":             true,
+			"stat/file.txt#info": true,
+		},
+		vanished: map[string]bool{"stat/gone.txt": true},
+	}
+
+	paths := func(node *Node) map[string]string {
+		result := map[string]string{}
+		var walk func(*Node)
+		walk = func(n *Node) {
+			for _, child := range n.Children {
+				result[child.Path] = child.Error
+				walk(child)
+			}
+		}
+		walk(node)
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		policy   ErrorPolicy
+		expected map[string]string
+	}{
+		{
+			name:   "Skip",
+			policy: SkipOnError,
+			expected: map[string]string{
+				"ok":               "",
+				"ok/file.txt":      "",
+				"stat":             "",
+				"stat/visible.txt": "",
+			},
+		},
+		{
+			name:   "Record",
+			policy: RecordOnError,
+			expected: map[string]string{
+				"locked":           "open locked: permission denied",
+				"ok":               "",
+				"ok/file.txt":      "",
+				"stat":             "",
+				"stat/file.txt":    "lstat file.txt: permission denied",
+				"stat/visible.txt": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: tt.policy})
+
+			var partialErr *PartialError
+			if !errors.As(err, &partialErr) {
+				t.Fatalf("Expected *PartialError, got %v", err)
+			}
+			if partialErr.Err != nil || len(partialErr.Unscanned) != 0 {
+				t.Errorf("Unexpected cancellation: %v", partialErr)
+			}
+			var failed []string
+			for _, failure := range partialErr.Failures {
+				failed = append(failed, failure.Path)
+				if !errors.Is(failure, fs.ErrPermission) {
+					t.Errorf("Failure %v does not wrap fs.ErrPermission", failure)
+				}
+			}
+			if !reflect.DeepEqual(failed, []string{"locked", "stat/file.txt"}) {
+				t.Errorf("Failures = %v, want [locked stat/file.txt]", failed)
+			}
+
+			if root == nil {
+				t.Fatal("Expected root node")
+			}
+			if result := paths(root); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Nodes = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	t.Run("Abort", func(t *testing.T) {
+		for _, policy := range []ErrorPolicy{"", AbortOnError} {
+			root, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: policy})
+			if root != nil || err == nil {
+				t.Fatalf("Expected error without tree, got %v, %v", root, err)
+			}
+			var partialErr *PartialError
+			if errors.As(err, &partialErr) || !errors.Is(err, fs.ErrPermission) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}
+	})
+
+	t.Run("Unreadable root", func(t *testing.T) {
+		root, err := BuildTreeFS(fsys, BuildOptions{Path: "locked", MaxDepth: -1, ErrorPolicy: SkipOnError})
+		if root == nil || root.Error == "" {
+			t.Fatalf("Expected root with error, got %+v", root)
+		}
+		if err == nil {
+			t.Error("Expected *PartialError")
+		}
+	})
+}
@@ -0,0 +1,437 @@
+package tree
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a compiled find(1)-style filter expression. Nodes below the
+// scan root that do not match are dropped, and directories are kept only
+// if they match themselves or still have children afterwards.
+//
+// Supported tests:
+//
+//	-name GLOB     base name matches a path.Match glob
+//	-iname GLOB    like -name, ignoring case
+//	-path PATTERN  relative path matches a pattern as accepted by ParsePattern
+//	-type f,d,l    node is a file, directory or symbolic link
+//	-size [+-]N[bckMGT]     size, rounded up to whole units, greater than, less than or equal to N (512-byte blocks by default, like find; c for bytes)
+//	-mtime [+-]N[smhdw]     time since modification, in whole units, greater than, less than or equal to N (days by default)
+//	-atime [+-]N[smhdw]     like -mtime, for the last access
+//	-perm [-/]MODE          octal permission bits are exactly, all of (-) or any of (/) MODE
+//	-user NAME|UID          owned by the user
+//	-group NAME|GID         owned by the group
+//
+// Tests are combined with "!" or -not, -a or -and (also implied between
+// adjacent tests), -o or -or, and grouped with parentheses.
+type Filter struct {
+	expr  string
+	match filterFunc
+}
+
+// filterFunc reports whether an entry matches a filter expression
+type filterFunc func(e *filterEntry) bool
+
+// filterEntry is the node a filter is evaluated against
+type filterEntry struct {
+	name  string
+	rel   string // slash separated path relative to the scan root
+	typ   FileType
+	info  fs.FileInfo
+	now   time.Time
+	names *nameCache
+
+	sys     sysInfo
+	sysOK   bool
+	sysDone bool
+}
+
+// stat returns the platform specific status of the entry, if available
+func (e *filterEntry) stat() (sysInfo, bool) {
+	if !e.sysDone {
+		e.sys, e.sysOK = sysStat(e.info)
+		e.sysDone = true
+	}
+	return e.sys, e.sysOK
+}
+
+// ParseFilter compiles a filter expression such as
+// "-type f -size +100M -mtime +90" or "-perm /002 -user root"
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := splitFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+
+	p := &filterParser{tokens: tokens}
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter expression", p.tokens[p.pos].text)
+	}
+	return &Filter{expr: expr, match: match}, nil
+}
+
+// String returns the expression the filter was parsed from
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// filterToken is a word of a filter expression
+type filterToken struct {
+	text   string
+	quoted bool // quoted words are never operators
+}
+
+// splitFilter splits an expression into words. Words are separated by
+// spaces, may be quoted with ' or ", and unquoted parentheses are words
+// of their own.
+func splitFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	var word strings.Builder
+	inWord, quoted := false, false
+	var quote rune
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, filterToken{text: word.String(), quoted: quoted})
+		}
+		word.Reset()
+		inWord, quoted = false, false
+	}
+
+	for _, r := range expr {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord, quoted = true, true
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, filterToken{text: string(r)})
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in filter expression")
+	}
+	flush()
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser for filter expressions
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// peek returns the next operator word, or "" for operands and end of input
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+// parseOr parses alternatives joined by -o
+func (p *filterParser) parseOr() (filterFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *filterEntry) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+// parseAnd parses terms joined by -a or by juxtaposition
+func (p *filterParser) parseAnd() (filterFunc, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case ")", "-o", "-or":
+			return left, nil
+		case "-a", "-and":
+			p.pos++
+		default:
+			if p.pos >= len(p.tokens) {
+				return left, nil
+			}
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *filterEntry) bool { return l(e) && right(e) }
+	}
+}
+
+// parseNot parses negations, parenthesised expressions and tests
+func (p *filterParser) parseNot() (filterFunc, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("filter expression ends unexpectedly")
+	}
+
+	switch p.peek() {
+	case "!", "-not":
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *filterEntry) bool { return !operand(e) }, nil
+	case "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in filter expression")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return p.parseTest()
+}
+
+// parseTest parses a single test and its argument
+func (p *filterParser) parseTest() (filterFunc, error) {
+	name := p.tokens[p.pos].text
+	if p.tokens[p.pos].quoted || !strings.HasPrefix(name, "-") {
+		return nil, fmt.Errorf("expected a test in filter expression, got %q", name)
+	}
+	if p.pos+1 >= len(p.tokens) {
+		return nil, fmt.Errorf("missing argument to %s", name)
+	}
+	arg := p.tokens[p.pos+1].text
+	p.pos += 2
+
+	test, err := compileTest(name, arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", name, arg, err)
+	}
+	return test, nil
+}
+
+// sizeUnits are the multipliers accepted by -size
+var sizeUnits = map[byte]int64{
+	'b': 512,
+	'c': 1,
+	'k': 1 << 10,
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+}
+
+// timeUnits are the multipliers accepted by -mtime and -atime
+var timeUnits = map[byte]int64{
+	's': int64(time.Second),
+	'm': int64(time.Minute),
+	'h': int64(time.Hour),
+	'd': int64(24 * time.Hour),
+	'w': int64(7 * 24 * time.Hour),
+}
+
+// compileTest compiles the test called name with its argument
+func compileTest(name, arg string) (filterFunc, error) {
+	switch name {
+	case "-name", "-iname":
+		fold := name == "-iname"
+		if fold {
+			arg = strings.ToLower(arg)
+		}
+		if _, err := path.Match(arg, ""); err != nil {
+			return nil, err
+		}
+		return func(e *filterEntry) bool {
+			base := e.name
+			if fold {
+				base = strings.ToLower(base)
+			}
+			matched, _ := path.Match(arg, base)
+			return matched
+		}, nil
+
+	case "-path":
+		pattern, err := ParsePattern(arg)
+		if err != nil {
+			return nil, err
+		}
+		match, err := pattern.compile()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *filterEntry) bool { return match(e.rel) }, nil
+
+	case "-type":
+		var types []FileType
+		for _, t := range strings.Split(arg, ",") {
+			switch t {
+			case "f":
+				types = append(types, File)
+			case "d":
+				types = append(types, Directory)
+			case "l":
+				types = append(types, Symlink)
+			default:
+				return nil, fmt.Errorf("unknown type %q", t)
+			}
+		}
+		return func(e *filterEntry) bool {
+			for _, t := range types {
+				if e.typ == t {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "-size":
+		cmp, n, unit, err := parseComparison(arg, sizeUnits, sizeUnits['b'])
+		if err != nil {
+			return nil, err
+		}
+		return func(e *filterEntry) bool {
+			// Like find, sizes are rounded up, so -size -1M only matches empty files
+			size := e.info.Size()
+			return compareUnits(cmp, (size+unit-1)/unit, n)
+		}, nil
+
+	case "-mtime", "-atime":
+		cmp, n, unit, err := parseComparison(arg, timeUnits, timeUnits['d'])
+		if err != nil {
+			return nil, err
+		}
+		access := name == "-atime"
+		return func(e *filterEntry) bool {
+			t := e.info.ModTime()
+			if access {
+				sys, ok := e.stat()
+				if !ok {
+					return false
+				}
+				t = sys.atime
+			}
+			// Like find, the remainder is ignored, so -mtime +1 matches ages of two days or more
+			age := int64(e.now.Sub(t))
+			count := age / unit
+			if age < 0 && age%unit != 0 {
+				count--
+			}
+			return compareUnits(cmp, count, n)
+		}, nil
+
+	case "-perm":
+		mode := ""
+		if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "/") {
+			mode, arg = arg[:1], arg[1:]
+		}
+		bits, err := strconv.ParseUint(arg, 8, 32)
+		if err != nil || bits > 0o7777 {
+			return nil, fmt.Errorf("expected octal permission bits")
+		}
+		want := uint32(bits)
+		return func(e *filterEntry) bool {
+			perm := permBits(e.info.Mode())
+			switch mode {
+			case "-":
+				return perm&want == want
+			case "/":
+				return perm&want != 0
+			}
+			return perm == want
+		}, nil
+
+	case "-user", "-group":
+		group := name == "-group"
+		id, numeric := uint32(0), false
+		if n, err := strconv.ParseUint(arg, 10, 32); err == nil {
+			id, numeric = uint32(n), true
+		}
+		return func(e *filterEntry) bool {
+			sys, ok := e.stat()
+			if !ok {
+				return false
+			}
+			owner := sys.uid
+			if group {
+				owner = sys.gid
+			}
+			if numeric {
+				return owner == id
+			}
+			if group {
+				return e.names.group(owner) == arg
+			}
+			return e.names.user(owner) == arg
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown test")
+}
+
+// compareUnits compares a count of whole units with N by the comparison of
+// a [+-]N argument
+func compareUnits(cmp byte, count, n int64) bool {
+	switch cmp {
+	case '+':
+		return count > n
+	case '-':
+		return count < n
+	}
+	return count == n
+}
+
+// parseComparison parses a find(1) numeric argument of the form [+-]N[unit].
+// It returns the comparison ('+', '-' or '='), N and the unit multiplier.
+func parseComparison(arg string, units map[byte]int64, defaultUnit int64) (byte, int64, int64, error) {
+	cmp := byte('=')
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		cmp, arg = arg[0], arg[1:]
+	}
+
+	unit := defaultUnit
+	if arg != "" {
+		if u, ok := units[arg[len(arg)-1]]; ok {
+			unit, arg = u, arg[:len(arg)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || n < 0 {
+		return 0, 0, 0, fmt.Errorf("expected [+-]N with an optional unit")
+	}
+	return cmp, n, unit, nil
+}
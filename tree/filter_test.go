@@ -0,0 +1,254 @@
+package tree
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// TestParseFilter tests filter expression syntax
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"-name *.go", false},
+		{"-type f -size +100M -mtime +90", false},
+		{"-type f -a ( -name '*.log' -o -iname \"*.TMP\" )", false},
+		{"! -perm /002 -user root", false},
+		{"-not -group 0 -and -atime -2h", false},
+		{"-path re:^cmd/", false},
+		{"", true},
+		{"-name", true},
+		{"-size 10X", true},
+		{"-size +-1", true},
+		{"-perm 999", true},
+		{"-type x", true},
+		{"-bogus 1", true},
+		{"( -name a", true},
+		{"-name a )", true},
+		{"-name a -o", true},
+		{"-name 'a", true},
+		{"name a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if err == nil && f.String() != tt.expr {
+				t.Errorf("String() = %q, want %q", f.String(), tt.expr)
+			}
+		})
+	}
+}
+
+// TestBuildTreeFilter tests filtering and pruning during traversal
+func TestBuildTreeFilter(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-100 * 24 * time.Hour)
+	fsys := fstest.MapFS{
+		"big.iso":            {Data: make([]byte, 3<<20), ModTime: old, Mode: 0o644},
+		"new.iso":            {Data: make([]byte, 3<<20), ModTime: now, Mode: 0o644},
+		"notes.txt":          {Data: []byte("hi"), ModTime: old, Mode: 0o666},
+		"Notes.TXT":          {Data: []byte("hello"), ModTime: now, Mode: 0o600},
+		"logs/app.log":       {Data: make([]byte, 2<<10), ModTime: now, Mode: 0o644},
+		"logs/old/app.log":   {Data: make([]byte, 1<<10), ModTime: old, Mode: 0o644},
+		"bin/run":            {Data: []byte("#!"), ModTime: now, Mode: 0o755 | fs.ModeSetuid},
+		"vendor/lib/lib.go":  {Data: []byte("package lib"), ModTime: now, Mode: 0o644},
+		"empty/placeholder":  {ModTime: now, Mode: 0o644},
+		"empty/nested/.keep": {ModTime: now, Mode: 0o644},
+	}
+
+	paths := func(node *Node) []string {
+		var result []string
+		var walk func(*Node)
+		walk = func(n *Node) {
+			for _, child := range n.Children {
+				result = append(result, child.Path)
+				walk(child)
+			}
+		}
+		walk(node)
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected []string
+	}{
+		{
+			name:     "Large and old",
+			expr:     "-type f -size +1M -mtime +90",
+			expected: []string{"big.iso"},
+		},
+		{
+			name:     "Size range",
+			expr:     "-size +1k -size -3M",
+			expected: []string{"logs", "logs/app.log"},
+		},
+		{
+			name:     "Exact size rounds up",
+			expr:     "-type f -size 1k",
+			expected: []string{"Notes.TXT", "bin", "bin/run", "logs", "logs/old", "logs/old/app.log", "notes.txt", "vendor", "vendor/lib", "vendor/lib/lib.go"},
+		},
+		{
+			name:     "Blocks by default",
+			expr:     "-type f -size 2",
+			expected: []string{"logs", "logs/old", "logs/old/app.log"},
+		},
+		{
+			name:     "Larger than blocks",
+			expr:     "-type f -size +2",
+			expected: []string{"big.iso", "logs", "logs/app.log", "new.iso"},
+		},
+		{
+			name:     "Bytes",
+			expr:     "-size 5c",
+			expected: []string{"Notes.TXT"},
+		},
+		{
+			name:     "Case-insensitive name or",
+			expr:     "-iname '*.txt' -o -name '*.go'",
+			expected: []string{"Notes.TXT", "notes.txt", "vendor", "vendor/lib", "vendor/lib/lib.go"},
+		},
+		{
+			name:     "Recent logs",
+			expr:     "-name *.log -mtime -1",
+			expected: []string{"logs", "logs/app.log"},
+		},
+		{
+			name:     "Perm bits",
+			expr:     "-perm /002 -o -perm -4000",
+			expected: []string{"bin", "bin/run", "notes.txt"},
+		},
+		{
+			name:     "Exact perm",
+			expr:     "-perm 600",
+			expected: []string{"Notes.TXT"},
+		},
+		{
+			name:     "Matching directory kept without children",
+			expr:     "-type d -name old",
+			expected: []string{"logs", "logs/old"},
+		},
+		{
+			name:     "Negated path pattern",
+			expr:     "-type f ! ( -path logs/** -o -path **/vendor/** ) -size -2k",
+			expected: []string{"Notes.TXT", "bin", "bin/run", "empty", "empty/nested", "empty/nested/.keep", "empty/placeholder", "notes.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) returned error: %v", tt.expr, err)
+			}
+			root, err := BuildTreeFS(fsys, BuildOptions{
+				MaxDepth:     -1,
+				IncludeFiles: true,
+				Filter:       filter,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := paths(root); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Paths = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestBuildTreeFilterRounding tests that sizes and ages are counted in whole units, like find
+func TestBuildTreeFilterRounding(t *testing.T) {
+	now := time.Now()
+	fsys := fstest.MapFS{
+		"empty":   {ModTime: now},
+		"new.bin": {Data: make([]byte, 1<<10+1), ModTime: now.Add(-30 * time.Minute)},
+		"old.txt": {Data: []byte("x"), ModTime: now.Add(-36 * time.Hour)},
+	}
+
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{"-size -1k", []string{"empty"}},
+		{"-size -2k", []string{"empty", "old.txt"}},
+		{"-size 2k", []string{"new.bin"}},
+		{"-size +1k", []string{"new.bin"}},
+		{"-mtime +0", []string{"old.txt"}},
+		{"-mtime +1", nil},
+		{"-mtime 1", []string{"old.txt"}},
+		{"-mtime -1", []string{"empty", "new.bin"}},
+		{"-mtime +29m", []string{"new.bin", "old.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) returned error: %v", tt.expr, err)
+			}
+			root, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true, Filter: filter})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var names []string
+			for _, child := range root.Children {
+				names = append(names, child.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Children = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+}
+
+// TestBuildTreeFilterOwner tests owner tests against a real directory
+func TestBuildTreeFilterOwner(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("ownership is only available on Linux")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	uid := strconv.Itoa(os.Getuid())
+	tests := []struct {
+		expr     string
+		expected int
+	}{
+		{"-user " + uid, 1},
+		{"! -user " + uid, 0},
+		{"-group " + strconv.Itoa(os.Getgid()), 1},
+		{"-user no-such-user-dirtree", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) returned error: %v", tt.expr, err)
+			}
+			root, err := BuildTree(BuildOptions{Path: dir, MaxDepth: -1, IncludeFiles: true, Filter: filter})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(root.Children) != tt.expected {
+				t.Errorf("Expected %d children, got %d", tt.expected, len(root.Children))
+			}
+		})
+	}
+}
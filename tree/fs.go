@@ -0,0 +1,157 @@
+package tree
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// LstatFS is implemented by file systems that can describe a symbolic link
+// itself rather than the file it points to
+type LstatFS interface {
+	fs.FS
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// ReadLinkFS is implemented by file systems that can read symbolic link targets
+type ReadLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// maxLinkHops limits how many symbolic links are resolved in a chain
+const maxLinkHops = 40
+
+// errLinkOutsideFS reports a link target that cannot be expressed in the file system
+var errLinkOutsideFS = errors.New("link target outside file system")
+
+// DirFS returns a file system for the tree rooted at dir. Unlike os.DirFS,
+// it also implements LstatFS and ReadLinkFS.
+func DirFS(dir string) fs.FS {
+	return osFS{root: dir}
+}
+
+// osFS implements the file system abstraction on top of the os package.
+// With an empty root it accepts native OS paths instead of fs.ValidPath
+// names, which is what BuildTree uses.
+type osFS struct {
+	root string
+}
+
+// nativePath converts a file system name to an OS path
+func (f osFS) nativePath(op, name string) (string, error) {
+	if f.root == "" {
+		return name, nil
+	}
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(f.root, filepath.FromSlash(name)), nil
+}
+
+// Open implements fs.FS
+func (f osFS) Open(name string) (fs.File, error) {
+	p, err := f.nativePath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Stat implements fs.StatFS
+func (f osFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := f.nativePath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
+
+// Lstat implements LstatFS
+func (f osFS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := f.nativePath("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(p)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (f osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := f.nativePath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(p)
+}
+
+// ReadLink implements ReadLinkFS
+func (f osFS) ReadLink(name string) (string, error) {
+	p, err := f.nativePath("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(p)
+}
+
+// isNative reports whether fsys uses native OS paths rather than fs.ValidPath names
+func isNative(fsys fs.FS) bool {
+	f, ok := fsys.(osFS)
+	return ok && f.root == ""
+}
+
+// lstat describes name without following a final symbolic link when fsys
+// supports it, and falls back to fs.Stat otherwise
+func lstat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if l, ok := fsys.(LstatFS); ok {
+		return l.Lstat(name)
+	}
+	return fs.Stat(fsys, name)
+}
+
+// readLink returns the target of the symbolic link name
+func readLink(fsys fs.FS, name string) (string, error) {
+	if r, ok := fsys.(ReadLinkFS); ok {
+		return r.ReadLink(name)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+}
+
+// evalSymlinks resolves the symbolic link name to the name of its final target
+func evalSymlinks(fsys fs.FS, name string) (string, error) {
+	if isNative(fsys) {
+		return filepath.EvalSymlinks(name)
+	}
+	return resolveLinkChain(fsys, name)
+}
+
+// resolveLinkChain follows symbolic links with ReadLink until it reaches a
+// name that is not a link. Targets must stay inside fsys.
+func resolveLinkChain(fsys fs.FS, name string) (string, error) {
+	for i := 0; i < maxLinkHops; i++ {
+		info, err := lstat(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return name, nil
+		}
+
+		target, err := readLink(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			return "", &fs.PathError{Op: "readlink", Path: name, Err: errLinkOutsideFS}
+		}
+
+		next := path.Join(path.Dir(name), target)
+		if !fs.ValidPath(next) {
+			return "", &fs.PathError{Op: "readlink", Path: name, Err: errLinkOutsideFS}
+		}
+		name = next
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.New("too many links")}
+}
@@ -0,0 +1,71 @@
+package tree
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// HashAlgorithm selects how file contents are digested, see BuildOptions.Hash
+type HashAlgorithm string
+
+const (
+	SHA256 HashAlgorithm = "sha256"
+	SHA1   HashAlgorithm = "sha1"
+	MD5    HashAlgorithm = "md5"
+)
+
+// ParseHashAlgorithm validates a hash algorithm name. The empty string
+// disables hashing.
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	switch algorithm := HashAlgorithm(s); algorithm {
+	case "", SHA256, SHA1, MD5:
+		return algorithm, nil
+	}
+	return "", fmt.Errorf("unsupported hash algorithm: %s", s)
+}
+
+// new returns a hash computing the digest of the algorithm
+func (a HashAlgorithm) new() hash.Hash {
+	switch a {
+	case SHA1:
+		return sha1.New()
+	case MD5:
+		return md5.New()
+	}
+	return sha256.New()
+}
+
+// hashFile fills in the content digest of the file candidate c. Under
+// the skip and record error policies an unreadable file is skipped or
+// kept with its error; nil is returned if it is skipped.
+func (b *builder) hashFile(c *candidate) (*Node, error) {
+	digest, err := b.digest(c.name)
+	if err != nil {
+		if !b.opts.ErrorPolicy.tolerant() {
+			return nil, fmt.Errorf("error hashing %s: %w", c.node.Path, err)
+		}
+		return b.recordFailure(c.node, err, c.v.depth), nil
+	}
+	c.node.Hash = string(b.opts.Hash) + ":" + digest
+	return c.node, nil
+}
+
+// digest returns the hex encoded digest of the file called name in fsys
+func (b *builder) digest(name string) (string, error) {
+	file, err := b.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := b.opts.Hash.new()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
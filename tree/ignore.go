@@ -0,0 +1,215 @@
+package tree
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Names of the files holding ignore rules
+const (
+	gitIgnoreFile     = ".gitignore"
+	dirtreeIgnoreFile = ".dirtreeignore"
+	gitExcludeFile    = ".git/info/exclude"
+)
+
+// ignorePattern is a single line of a gitignore file
+type ignorePattern struct {
+	re       *regexp.Regexp
+	negate   bool // "!" prefix re-includes matching paths
+	dirOnly  bool // trailing "/" only matches directories
+	anchored bool // pattern contains a "/" and is matched against the whole path
+}
+
+// ignoreRules holds the patterns of one ignore file
+type ignoreRules struct {
+	base     string // directory of the file, relative to the ignore root
+	patterns []ignorePattern
+}
+
+// ignoreStack is the chain of rules applying to a directory, innermost first
+type ignoreStack struct {
+	rules  *ignoreRules
+	parent *ignoreStack
+}
+
+// parseIgnoreRules parses the content of a gitignore file located in base.
+// Lines that cannot be compiled are skipped, as git does.
+func parseIgnoreRules(data []byte, base string) *ignoreRules {
+	rules := &ignoreRules{base: base}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := trimIgnoreLine(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p ignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A slash at the beginning or in the middle anchors the pattern
+		// to the directory of the ignore file
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		re, err := compileWildcard(line)
+		if err != nil {
+			continue
+		}
+		p.re = re
+		rules.patterns = append(rules.patterns, p)
+	}
+
+	return rules
+}
+
+// trimIgnoreLine removes the line ending and unescaped trailing spaces
+func trimIgnoreLine(line string) string {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-2] + " "
+	}
+	return line
+}
+
+// match reports whether the rules decide about rel, a slash separated path
+// relative to the ignore root, and whether it is ignored. The last matching
+// pattern wins.
+func (r *ignoreRules) match(rel string, isDir bool) (matched, ignored bool) {
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false, false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+
+	for i := len(r.patterns) - 1; i >= 0; i-- {
+		p := r.patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+		subject := rel
+		if !p.anchored {
+			subject = path.Base(rel)
+		}
+		if p.re.MatchString(subject) {
+			return true, !p.negate
+		}
+	}
+	return false, false
+}
+
+// push returns a new stack with rules on top, or s itself if rules is empty
+func (s *ignoreStack) push(rules *ignoreRules) *ignoreStack {
+	if rules == nil || len(rules.patterns) == 0 {
+		return s
+	}
+	return &ignoreStack{rules: rules, parent: s}
+}
+
+// ignored reports whether rel is ignored. Rules from deeper directories
+// take precedence over rules from their parents.
+func (s *ignoreStack) ignored(rel string, isDir bool) bool {
+	for ; s != nil; s = s.parent {
+		if matched, ignored := s.rules.match(rel, isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// loadIgnoreRules reads the ignore files of the directory name, whose path
+// relative to the ignore root is base, and pushes them onto parent
+func (b *builder) loadIgnoreRules(parent *ignoreStack, name, base string) *ignoreStack {
+	stack := parent
+	if b.opts.GitIgnore {
+		stack = stack.push(b.readIgnoreFile(b.join(name, gitIgnoreFile), base))
+	}
+	if b.opts.DirtreeIgnore {
+		stack = stack.push(b.readIgnoreFile(b.join(name, dirtreeIgnoreFile), base))
+	}
+	return stack
+}
+
+// readIgnoreFile parses the ignore file name, returning nil if it cannot be read
+func (b *builder) readIgnoreFile(name, base string) *ignoreRules {
+	data, err := fs.ReadFile(b.fsys, name)
+	if err != nil {
+		return nil
+	}
+	return parseIgnoreRules(data, base)
+}
+
+// rootIgnores prepares the ignore rules that apply above the scan root.
+// With GitIgnore enabled the enclosing repository is searched for, so that
+// .git/info/exclude and the .gitignore files between the repository root and
+// the scan root apply as they do for git. It returns the rules and the scan
+// root's path relative to the ignore root.
+func (b *builder) rootIgnores() (*ignoreStack, string) {
+	if !b.opts.GitIgnore {
+		return nil, ""
+	}
+
+	// Collect the scan root and its ancestors, innermost first
+	dirs := []string{b.opts.Path}
+	if b.native {
+		if abs, err := filepath.Abs(b.opts.Path); err == nil {
+			dirs[0] = abs
+			for dir := filepath.Dir(abs); dir != dirs[len(dirs)-1]; dir = filepath.Dir(dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	} else {
+		for dir := b.opts.Path; dir != "."; {
+			dir = path.Dir(dir)
+			dirs = append(dirs, dir)
+		}
+	}
+
+	repo := -1
+	for i, dir := range dirs {
+		if _, err := fs.Stat(b.fsys, b.join(dir, ".git")); err == nil {
+			repo = i
+			break
+		}
+	}
+	if repo < 0 {
+		return nil, ""
+	}
+
+	// Relative path of each directory from the repository root
+	rel := make([]string, repo+1)
+	for i := repo - 1; i >= 0; i-- {
+		rel[i] = path.Join(rel[i+1], path.Base(filepath.ToSlash(dirs[i])))
+	}
+
+	var stack *ignoreStack
+	stack = stack.push(b.readIgnoreFile(b.join(dirs[repo], gitExcludeFile), ""))
+	for i := repo; i > 0; i-- {
+		stack = b.loadIgnoreRules(stack, dirs[i], rel[i])
+	}
+	return stack, rel[0]
+}
@@ -0,0 +1,127 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// TestIgnoreRules tests gitignore pattern semantics
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules([]byte(`# comment
+*.log
+!keep.log
+build/
+/root-only.txt
+docs/*.md
+**/tmp/**
+\#literal
+trailing\ 
+`), "")
+	stack := (*ignoreStack)(nil).push(rules)
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"app.log", false, true},
+		{"sub/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"sub/build", true, true},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"docs/readme.md", false, true},
+		{"docs/api/readme.md", false, false},
+		{"a/tmp/file", false, true},
+		{"#literal", false, true},
+		{"trailing ", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if result := stack.ignored(tt.path, tt.isDir); result != tt.expected {
+				t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestBuildTreeGitIgnore tests traversal honouring ignore files
+func TestBuildTreeGitIgnore(t *testing.T) {
+	fsys := fstest.MapFS{
+		".git/HEAD":           {},
+		".git/info/exclude":   {Data: []byte("*.swp\n")},
+		".gitignore":          {Data: []byte("*.log\nbin/\n")},
+		".dirtreeignore":      {Data: []byte("secret.txt\n")},
+		"app.log":             {},
+		"main.go":             {},
+		"main.go.swp":         {},
+		"secret.txt":          {},
+		"bin/app":             {},
+		"src/.gitignore":      {Data: []byte("!important.log\ngen/\n")},
+		"src/important.log":   {},
+		"src/other.log":       {},
+		"src/gen/code.go":     {},
+		"src/lib.go":          {},
+		"src/nested/bin/tool": {},
+	}
+
+	collect := func(node *Node) []string {
+		var paths []string
+		var walk func(*Node)
+		walk = func(n *Node) {
+			paths = append(paths, n.Path)
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		walk(node)
+		return paths
+	}
+
+	tests := []struct {
+		name     string
+		opts     BuildOptions
+		expected []string
+	}{
+		{
+			name: "Git ignore",
+			opts: BuildOptions{MaxDepth: -1, IncludeFiles: true, GitIgnore: true},
+			expected: []string{
+				".", ".dirtreeignore", ".gitignore", "main.go", "secret.txt", "src",
+				"src/.gitignore", "src/important.log", "src/lib.go", "src/nested",
+			},
+		},
+		{
+			name: "Git and dirtree ignore",
+			opts: BuildOptions{MaxDepth: -1, IncludeFiles: true, GitIgnore: true, DirtreeIgnore: true},
+			expected: []string{
+				".", ".dirtreeignore", ".gitignore", "main.go", "src",
+				"src/.gitignore", "src/important.log", "src/lib.go", "src/nested",
+			},
+		},
+		{
+			name: "Subdirectory of repository",
+			opts: BuildOptions{Path: "src", MaxDepth: -1, IncludeFiles: true, GitIgnore: true},
+			expected: []string{
+				"src", "src/.gitignore", "src/important.log", "src/lib.go", "src/nested",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := BuildTreeFS(fsys, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := collect(root); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Paths = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package tree
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileWildcard converts a gitignore-style wildcard pattern into an
+// anchored regular expression matching slash separated paths.
+// "*" and "?" never match "/", "[...]" is a character class and "**"
+// matches any number of directories when it forms a whole path segment.
+func compileWildcard(pattern string) (*regexp.Regexp, error) {
+	p := []rune(pattern)
+
+	var re strings.Builder
+	re.WriteString("^")
+
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' && (i == 0 || p[i-1] == '/') {
+				switch {
+				case i+2 == len(p):
+					// Trailing "**" matches everything below
+					re.WriteString(".*")
+					i++
+					continue
+				case p[i+2] == '/':
+					// "**/" matches zero or more directories
+					re.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				}
+			}
+			re.WriteString("[^/]*")
+			// Other consecutive asterisks behave like a single one
+			for i+1 < len(p) && p[i+1] == '*' {
+				i++
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			end, class, err := compileClass(p, i)
+			if err != nil {
+				return nil, fmt.Errorf("%w in pattern %q", err, pattern)
+			}
+			re.WriteString(class)
+			i = end
+		case '\\':
+			if i+1 < len(p) {
+				i++
+			}
+			re.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// compileClass converts the bracket expression starting at p[start] into a
+// regular expression class and returns the index of its closing "]"
+func compileClass(p []rune, start int) (int, string, error) {
+	var class strings.Builder
+	class.WriteString("[")
+
+	i := start + 1
+	if i < len(p) && (p[i] == '!' || p[i] == '^') {
+		class.WriteString("^/")
+		i++
+	}
+
+	first := true
+	for ; i < len(p); i++ {
+		c := p[i]
+		if c == ']' && !first {
+			class.WriteString("]")
+			return i, class.String(), nil
+		}
+		first = false
+
+		if c == '\\' && i+1 < len(p) {
+			i++
+			c = p[i]
+		} else if c == '-' && i+1 < len(p) && p[i+1] != ']' {
+			// Range operator between the previous and the next character
+			class.WriteRune('-')
+			continue
+		}
+		class.WriteString(regexp.QuoteMeta(string(c)))
+	}
+	return 0, "", fmt.Errorf("unterminated character class")
+}
@@ -0,0 +1,50 @@
+package tree
+
+import "testing"
+
+// TestCompileWildcard tests wildcard to regexp conversion
+func TestCompileWildcard(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"[abc].txt", "b.txt", true},
+		{"[!abc].txt", "b.txt", false},
+		{"[a-c].txt", "c.txt", true},
+		{"[a-c].txt", "d.txt", false},
+		{"**/vendor", "vendor", true},
+		{"**/vendor", "a/b/vendor", true},
+		{"vendor/**", "vendor/x/y.go", true},
+		{"vendor/**", "vendor", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "ab", false},
+		{"foo**bar", "fooxbar", true},
+		{"foo**bar", "foo/bar", false},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+		{"my.git", "myxgit", false},
+		{"ü*.txt", "über.txt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			re, err := compileWildcard(tt.pattern)
+			if err != nil {
+				t.Fatalf("compileWildcard(%q) returned error: %v", tt.pattern, err)
+			}
+			if result := re.MatchString(tt.path); result != tt.expected {
+				t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.path, result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := compileWildcard("[abc"); err == nil {
+		t.Error("Expected error for unterminated character class")
+	}
+}
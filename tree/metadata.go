@@ -0,0 +1,152 @@
+package tree
+
+import (
+	"fmt"
+	"io/fs"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sysInfo holds the platform specific parts of a file's status
+type sysInfo struct {
+	dev, ino uint64
+	nlink    uint64
+	uid, gid uint32
+	blocks   int64 // Number of 512-byte blocks allocated
+	atime    time.Time
+	ctime    time.Time
+}
+
+// nameCache resolves user and group ids to names, remembering the results
+type nameCache struct {
+	mu     sync.Mutex
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+// user returns the name of the user with the given id, or "" if unknown
+func (c *nameCache) user(uid uint32) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name, ok := c.users[uid]; ok {
+		return name
+	}
+	if c.users == nil {
+		c.users = make(map[uint32]string)
+	}
+	name := ""
+	if u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10)); err == nil {
+		name = u.Username
+	}
+	c.users[uid] = name
+	return name
+}
+
+// group returns the name of the group with the given id, or "" if unknown
+func (c *nameCache) group(gid uint32) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name, ok := c.groups[gid]; ok {
+		return name
+	}
+	if c.groups == nil {
+		c.groups = make(map[uint32]string)
+	}
+	name := ""
+	if g, err := user.LookupGroupId(strconv.FormatUint(uint64(gid), 10)); err == nil {
+		name = g.Name
+	}
+	c.groups[gid] = name
+	return name
+}
+
+// fillMetadata sets the optional metadata fields of node from info.
+// linkName is the name of the symbolic link the node was reached through,
+// or "" if it is not a link.
+func (b *builder) fillMetadata(node *Node, info fs.FileInfo, linkName string) {
+	modTime := info.ModTime()
+	node.ModTime = &modTime
+	node.Mode = modeString(info.Mode())
+	node.Perm = fmt.Sprintf("%04o", permBits(info.Mode()))
+
+	if linkName != "" {
+		if target, err := readLink(b.fsys, linkName); err == nil {
+			node.LinkTarget = target
+		}
+	}
+
+	sys, ok := sysStat(info)
+	if !ok {
+		return
+	}
+	node.Inode = sys.ino
+	node.Device = sys.dev
+	node.Links = sys.nlink
+	node.AccessTime = &sys.atime
+	node.ChangeTime = &sys.ctime
+	node.UID = &sys.uid
+	node.GID = &sys.gid
+	node.Owner = b.names.user(sys.uid)
+	node.Group = b.names.group(sys.gid)
+}
+
+// permBits returns the permission bits of mode in Unix numeric form,
+// including the setuid, setgid and sticky bits
+func permBits(mode fs.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return bits
+}
+
+// modeString formats mode the way ls -l does, e.g. "drwxr-xr-x"
+func modeString(mode fs.FileMode) string {
+	buf := []byte("----------")
+
+	switch {
+	case mode&fs.ModeDir != 0:
+		buf[0] = 'd'
+	case mode&fs.ModeSymlink != 0:
+		buf[0] = 'l'
+	case mode&fs.ModeNamedPipe != 0:
+		buf[0] = 'p'
+	case mode&fs.ModeSocket != 0:
+		buf[0] = 's'
+	case mode&fs.ModeDevice != 0 && mode&fs.ModeCharDevice != 0:
+		buf[0] = 'c'
+	case mode&fs.ModeDevice != 0:
+		buf[0] = 'b'
+	}
+
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			buf[i+1] = rwx[i]
+		}
+	}
+
+	special := func(pos int, set bool, lower, upper byte) {
+		if !set {
+			return
+		}
+		if buf[pos] == 'x' {
+			buf[pos] = lower
+		} else {
+			buf[pos] = upper
+		}
+	}
+	special(3, mode&fs.ModeSetuid != 0, 's', 'S')
+	special(6, mode&fs.ModeSetgid != 0, 's', 'S')
+	special(9, mode&fs.ModeSticky != 0, 't', 'T')
+
+	return string(buf)
+}
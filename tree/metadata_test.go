@@ -0,0 +1,110 @@
+package tree
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestModeString tests ls-style permission strings
+func TestModeString(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     fs.FileMode
+		expected string
+	}{
+		{"Regular file", 0644, "-rw-r--r--"},
+		{"Directory", fs.ModeDir | 0755, "drwxr-xr-x"},
+		{"Symlink", fs.ModeSymlink | 0777, "lrwxrwxrwx"},
+		{"Setuid executable", fs.ModeSetuid | 0755, "-rwsr-xr-x"},
+		{"Setgid without exec", fs.ModeSetgid | 0640, "-rw-r-S---"},
+		{"Sticky directory", fs.ModeDir | fs.ModeSticky | 0777, "drwxrwxrwt"},
+		{"Named pipe", fs.ModeNamedPipe | 0600, "prw-------"},
+		{"Char device", fs.ModeDevice | fs.ModeCharDevice | 0666, "crw-rw-rw-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := modeString(tt.mode); result != tt.expected {
+				t.Errorf("modeString(%v) = %q, want %q", tt.mode, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPermBits tests numeric permission bits
+func TestPermBits(t *testing.T) {
+	tests := []struct {
+		mode     fs.FileMode
+		expected uint32
+	}{
+		{0644, 0o644},
+		{fs.ModeDir | 0755, 0o755},
+		{fs.ModeSetuid | 0755, 0o4755},
+		{fs.ModeDir | fs.ModeSticky | 0777, 0o1777},
+	}
+
+	for _, tt := range tests {
+		if result := permBits(tt.mode); result != tt.expected {
+			t.Errorf("permBits(%v) = %o, want %o", tt.mode, result, tt.expected)
+		}
+	}
+}
+
+// TestCollectMetadata tests that metadata is only collected when requested
+func TestCollectMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "file.txt")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Chmod(file, 0640); err != nil {
+		t.Fatalf("Failed to chmod test file: %v", err)
+	}
+	if err := os.Symlink("file.txt", filepath.Join(tmpDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	opts := BuildOptions{Path: tmpDir, MaxDepth: -1, IncludeFiles: true}
+
+	root, err := BuildTree(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if root.ModTime != nil || root.Mode != "" {
+		t.Error("Metadata should not be collected by default")
+	}
+
+	opts.CollectMetadata = true
+	root, err = BuildTree(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	node := root.Children[0]
+	if node.Name != "file.txt" {
+		t.Fatalf("Unexpected first child %s", node.Name)
+	}
+	if node.Mode != "-rw-r-----" || node.Perm != "0640" {
+		t.Errorf("Mode = %q, Perm = %q", node.Mode, node.Perm)
+	}
+	if node.ModTime == nil {
+		t.Error("ModTime should be set")
+	}
+
+	link := root.Children[1]
+	if link.LinkTarget != "file.txt" {
+		t.Errorf("LinkTarget = %q, want %q", link.LinkTarget, "file.txt")
+	}
+
+	if runtime.GOOS == "linux" {
+		if node.Inode == 0 || node.Links != 1 || node.AccessTime == nil || node.ChangeTime == nil {
+			t.Errorf("Missing inode metadata: %+v", node)
+		}
+		if node.UID == nil || int(*node.UID) != os.Getuid() {
+			t.Errorf("UID = %v, want %d", node.UID, os.Getuid())
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package tree
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PatternKind selects how a Pattern is matched
+type PatternKind string
+
+const (
+	GlobPattern       PatternKind = "glob"       // path.Match syntax, "*" does not cross "/"
+	DoubleStarPattern PatternKind = "doublestar" // Glob where "**" matches any number of directories
+	RegexPattern      PatternKind = "regex"      // Regular expression, unanchored
+)
+
+// Pattern selects paths relative to the scan root, using "/" as separator.
+// Glob and doublestar patterns without a "/" match the base name at any
+// depth; all other patterns match the whole relative path.
+type Pattern struct {
+	Kind PatternKind
+	Expr string
+}
+
+// ParsePattern parses a pattern of the form "kind:expr", where kind is
+// "glob", "doublestar" or "regex" (also "re"). Without a kind prefix the
+// pattern is a doublestar pattern if it contains "**" and a glob otherwise.
+func ParsePattern(s string) (Pattern, error) {
+	p := Pattern{Expr: s}
+	if kind, expr, ok := strings.Cut(s, ":"); ok {
+		switch PatternKind(kind) {
+		case GlobPattern, DoubleStarPattern, RegexPattern:
+			p = Pattern{Kind: PatternKind(kind), Expr: expr}
+		case "re":
+			p = Pattern{Kind: RegexPattern, Expr: expr}
+		}
+	}
+
+	if p.Kind == "" {
+		p.Kind = GlobPattern
+		if strings.Contains(p.Expr, "**") {
+			p.Kind = DoubleStarPattern
+		}
+	}
+
+	if _, err := p.compile(); err != nil {
+		return Pattern{}, err
+	}
+	return p, nil
+}
+
+// String returns the pattern in the form accepted by ParsePattern
+func (p Pattern) String() string {
+	return string(p.Kind) + ":" + p.Expr
+}
+
+// compile returns a function reporting whether a relative path matches p
+func (p Pattern) compile() (func(rel string) bool, error) {
+	// Patterns without a separator apply to the base name at any depth
+	subject := func(rel string) string { return rel }
+	if p.Kind != RegexPattern && !strings.Contains(p.Expr, "/") {
+		subject = path.Base
+	}
+
+	switch p.Kind {
+	case GlobPattern:
+		if _, err := path.Match(p.Expr, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", p.Expr, err)
+		}
+		return func(rel string) bool {
+			matched, _ := path.Match(p.Expr, subject(rel))
+			return matched
+		}, nil
+	case DoubleStarPattern:
+		re, err := compileWildcard(p.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid doublestar pattern %q: %w", p.Expr, err)
+		}
+		return func(rel string) bool { return re.MatchString(subject(rel)) }, nil
+	case RegexPattern:
+		re, err := regexp.Compile(p.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %q: %w", p.Expr, err)
+		}
+		return re.MatchString, nil
+	default:
+		return nil, fmt.Errorf("unknown pattern kind %q", p.Kind)
+	}
+}
+
+// compilePatterns compiles a list of patterns into matchers
+func compilePatterns(patterns []Pattern) ([]func(rel string) bool, error) {
+	matchers := make([]func(rel string) bool, 0, len(patterns))
+	for _, p := range patterns {
+		m, err := p.compile()
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// matchesAny reports whether rel matches at least one of the matchers
+func matchesAny(rel string, matchers []func(rel string) bool) bool {
+	for _, m := range matchers {
+		if m(rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// TestParsePattern tests pattern kind detection
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Pattern
+		wantErr  bool
+	}{
+		{"*.go", Pattern{Kind: GlobPattern, Expr: "*.go"}, false},
+		{"**/vendor/**", Pattern{Kind: DoubleStarPattern, Expr: "**/vendor/**"}, false},
+		{"glob:**", Pattern{Kind: GlobPattern, Expr: "**"}, false},
+		{"doublestar:vendor", Pattern{Kind: DoubleStarPattern, Expr: "vendor"}, false},
+		{"regex:^cmd/.*\\.go$", Pattern{Kind: RegexPattern, Expr: "^cmd/.*\\.go$"}, false},
+		{"re:test", Pattern{Kind: RegexPattern, Expr: "test"}, false},
+		{"unknown:x", Pattern{Kind: GlobPattern, Expr: "unknown:x"}, false},
+		{"re:[", Pattern{}, true},
+		{"glob:[", Pattern{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParsePattern(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePattern(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("ParsePattern(%q) = %+v, want %+v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPatternMatch tests matching relative paths
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		rel      string
+		expected bool
+	}{
+		{".git", ".git", true},
+		{".git", "sub/.git", true},
+		{".git", "my.gitlab-notes", false},
+		{"*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/sub/main.go", false},
+		{"**/vendor/**", "vendor/lib/x.go", true},
+		{"**/vendor/**", "a/vendor/x.go", true},
+		{"**/vendor/**", "vendors/x.go", false},
+		{"re:^docs/", "docs/readme.md", true},
+		{"re:^docs/", "src/docs/readme.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.rel, func(t *testing.T) {
+			p, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern(%q) returned error: %v", tt.pattern, err)
+			}
+			match, err := p.compile()
+			if err != nil {
+				t.Fatalf("compile() returned error: %v", err)
+			}
+			if result := match(tt.rel); result != tt.expected {
+				t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.rel, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestBuildTreePatterns tests include and exclude patterns during traversal
+func TestBuildTreePatterns(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":                {},
+		"README.md":              {},
+		"my.gitlab-notes":        {},
+		".git/HEAD":              {},
+		"cmd/tool/main.go":       {},
+		"cmd/tool/main_test.go":  {},
+		"vendor/lib/lib.go":      {},
+		"internal/vendor/x/x.go": {},
+	}
+
+	mustParse := func(patterns ...string) []Pattern {
+		result := make([]Pattern, 0, len(patterns))
+		for _, s := range patterns {
+			p, err := ParsePattern(s)
+			if err != nil {
+				t.Fatalf("ParsePattern(%q) returned error: %v", s, err)
+			}
+			result = append(result, p)
+		}
+		return result
+	}
+
+	files := func(node *Node) []string {
+		var paths []string
+		var walk func(*Node)
+		walk = func(n *Node) {
+			if n.Type == File {
+				paths = append(paths, n.Path)
+			}
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		walk(node)
+		return paths
+	}
+
+	tests := []struct {
+		name     string
+		exclude  []Pattern
+		include  []Pattern
+		expected []string
+	}{
+		{
+			name:     "Exclude exact name",
+			exclude:  mustParse(".git", "**/vendor/**"),
+			expected: []string{"README.md", "cmd/tool/main.go", "cmd/tool/main_test.go", "main.go", "my.gitlab-notes"},
+		},
+		{
+			name:     "Include go files",
+			exclude:  mustParse("*_test.go"),
+			include:  mustParse("*.go"),
+			expected: []string{"cmd/tool/main.go", "internal/vendor/x/x.go", "main.go", "vendor/lib/lib.go"},
+		},
+		{
+			name:     "Include anchored regex",
+			include:  mustParse("re:^cmd/"),
+			expected: []string{"cmd/tool/main.go", "cmd/tool/main_test.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := BuildTreeFS(fsys, BuildOptions{
+				MaxDepth:     -1,
+				IncludeFiles: true,
+				Exclude:      tt.exclude,
+				Include:      tt.include,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := files(root); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Files = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := BuildTreeFS(fsys, BuildOptions{Exclude: []Pattern{{Kind: RegexPattern, Expr: "["}}}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,152 @@
+package tree
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortKey selects the order of the children of each directory
+type SortKey string
+
+const (
+	SortByName       SortKey = "name"        // Byte-wise name order
+	SortByNatural    SortKey = "natural"     // Name order comparing digit runs as numbers, file2 < file10
+	SortByIgnoreCase SortKey = "ignore-case" // Case-insensitive name order
+	SortBySize       SortKey = "size"        // Size, total size for directories
+	SortByModTime    SortKey = "mtime"       // Modification time
+	SortByExtension  SortKey = "extension"   // Case-insensitive extension, then name
+	SortByDiskUsage  SortKey = "disk_usage"  // Disk usage, total disk usage for directories
+)
+
+// ParseSortKey parses a sort key name. "version" is accepted as an alias
+// of "natural", and "" leaves children in directory order. SortByDiskUsage
+// is not parsed: sorting by size selects it when sizes are shown as disk
+// usage.
+func ParseSortKey(s string) (SortKey, error) {
+	switch key := SortKey(s); key {
+	case "", SortByName, SortByNatural, SortByIgnoreCase, SortBySize, SortByModTime, SortByExtension:
+		return key, nil
+	case "version":
+		return SortByNatural, nil
+	}
+	return "", fmt.Errorf("unsupported sort key: %s", s)
+}
+
+// sortEntry is a child node with the data it is sorted by
+type sortEntry struct {
+	node      *Node
+	links     hardLinks
+	modTime   time.Time
+	candidate *candidate // set when walking instead of building
+}
+
+// sortChildren orders entries according to opts. Ties are broken by name
+// so the result does not depend on the order entries were read in.
+func sortChildren(entries []sortEntry, opts *BuildOptions) {
+	if opts.SortBy == "" && !opts.DirsFirst && !opts.FilesFirst {
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+
+		// Grouping is applied before, and independently of, the direction
+		if aDir, bDir := a.node.Type == Directory, b.node.Type == Directory; aDir != bDir {
+			if opts.DirsFirst {
+				return aDir
+			}
+			if opts.FilesFirst {
+				return bDir
+			}
+		}
+		if opts.SortBy == "" {
+			return false
+		}
+
+		c := compareBy(opts.SortBy, a, b)
+		if c == 0 {
+			c = strings.Compare(a.node.Name, b.node.Name)
+		}
+		if opts.SortDescending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// compareBy compares two entries by key, returning -1, 0 or +1
+func compareBy(key SortKey, a, b sortEntry) int {
+	switch key {
+	case SortByNatural:
+		return compareNatural(a.node.Name, b.node.Name)
+	case SortByIgnoreCase:
+		return strings.Compare(strings.ToLower(a.node.Name), strings.ToLower(b.node.Name))
+	case SortBySize:
+		return compareInt(a.node.Size, b.node.Size)
+	case SortByDiskUsage:
+		return compareInt(a.node.DiskUsage, b.node.DiskUsage)
+	case SortByModTime:
+		return a.modTime.Compare(b.modTime)
+	case SortByExtension:
+		return strings.Compare(strings.ToLower(filepath.Ext(a.node.Name)), strings.ToLower(filepath.Ext(b.node.Name)))
+	}
+	return strings.Compare(a.node.Name, b.node.Name)
+}
+
+// compareInt compares two integers, returning -1, 0 or +1
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareNatural compares names treating runs of digits as numbers, so
+// "file2" sorts before "file10". Numbers that are equal in value are
+// ordered by the number of leading zeros.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		aNum, bNum := isDigit(a[0]), isDigit(b[0])
+		if aNum != bNum {
+			return strings.Compare(a, b)
+		}
+
+		aRun, bRun := leadingRun(a, aNum), leadingRun(b, bNum)
+		if aNum {
+			aVal, bVal := strings.TrimLeft(aRun, "0"), strings.TrimLeft(bRun, "0")
+			if c := compareInt(int64(len(aVal)), int64(len(bVal))); c != 0 {
+				return c
+			}
+			if c := strings.Compare(aVal, bVal); c != 0 {
+				return c
+			}
+			if c := compareInt(int64(len(aRun)), int64(len(bRun))); c != 0 {
+				return c
+			}
+		} else if c := strings.Compare(aRun, bRun); c != 0 {
+			return c
+		}
+		a, b = a[len(aRun):], b[len(bRun):]
+	}
+	return compareInt(int64(len(a)), int64(len(b)))
+}
+
+// leadingRun returns the longest prefix of s made of digits or non-digits
+func leadingRun(s string, digits bool) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i]
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
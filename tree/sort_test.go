@@ -0,0 +1,131 @@
+package tree
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// TestCompareNatural tests natural name ordering
+func TestCompareNatural(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"file2", "file2", 0},
+		{"v1.2.10", "v1.10.0", -1},
+		{"a", "a1", -1},
+		{"file02", "file2", 1},
+		{"file2a", "file2b", -1},
+		{"10", "a", -1},
+		{"x99999999999999999999999", "x100000000000000000000000", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if result := compareNatural(tt.a, tt.b); result != tt.expected {
+				t.Errorf("compareNatural(%q, %q) = %d, want %d", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseSortKey tests sort key names and aliases
+func TestParseSortKey(t *testing.T) {
+	if key, err := ParseSortKey("version"); err != nil || key != SortByNatural {
+		t.Errorf("ParseSortKey(version) = %q, %v, want natural", key, err)
+	}
+	if key, err := ParseSortKey(""); err != nil || key != "" {
+		t.Errorf("ParseSortKey(\"\") = %q, %v, want empty key", key, err)
+	}
+	if _, err := ParseSortKey("random"); err == nil {
+		t.Error("Expected error for unknown sort key")
+	}
+}
+
+// TestBuildTreeSort tests sort modes during traversal
+func TestBuildTreeSort(t *testing.T) {
+	now := time.Now()
+	fsys := fstest.MapFS{
+		"file10.txt":   {Data: make([]byte, 10), ModTime: now.Add(-1 * time.Hour)},
+		"file2.txt":    {Data: make([]byte, 300), ModTime: now.Add(-3 * time.Hour)},
+		"B.md":         {Data: make([]byte, 20), ModTime: now.Add(-2 * time.Hour)},
+		"a.go":         {Data: make([]byte, 10), ModTime: now},
+		"dir/big.bin":  {Data: make([]byte, 100), ModTime: now},
+		"Zdir/x.bin":   {Data: make([]byte, 1), ModTime: now},
+		"Zdir/y.bin":   {Data: make([]byte, 1), ModTime: now},
+		"noext":        {Data: make([]byte, 5), ModTime: now.Add(-4 * time.Hour)},
+		"dir/.keep":    {ModTime: now},
+		"Zdir/sub/z.a": {ModTime: now},
+	}
+	fsys["dir"] = &fstest.MapFile{Mode: fs.ModeDir | 0o755, ModTime: now.Add(-5 * time.Hour)}
+	fsys["Zdir"] = &fstest.MapFile{Mode: fs.ModeDir | 0o755, ModTime: now.Add(-30 * time.Minute)}
+
+	tests := []struct {
+		name     string
+		opts     BuildOptions
+		expected []string
+	}{
+		{
+			name:     "Directory order",
+			expected: []string{"B.md", "Zdir", "a.go", "dir", "file10.txt", "file2.txt", "noext"},
+		},
+		{
+			name:     "Natural",
+			opts:     BuildOptions{SortBy: SortByNatural},
+			expected: []string{"B.md", "Zdir", "a.go", "dir", "file2.txt", "file10.txt", "noext"},
+		},
+		{
+			name:     "Ignore case descending",
+			opts:     BuildOptions{SortBy: SortByIgnoreCase, SortDescending: true},
+			expected: []string{"Zdir", "noext", "file2.txt", "file10.txt", "dir", "B.md", "a.go"},
+		},
+		{
+			name:     "Size with name ties",
+			opts:     BuildOptions{SortBy: SortBySize},
+			expected: []string{"Zdir", "noext", "a.go", "file10.txt", "B.md", "dir", "file2.txt"},
+		},
+		{
+			name:     "Modification time descending",
+			opts:     BuildOptions{SortBy: SortByModTime, SortDescending: true},
+			expected: []string{"a.go", "Zdir", "file10.txt", "B.md", "file2.txt", "noext", "dir"},
+		},
+		{
+			name:     "Extension",
+			opts:     BuildOptions{SortBy: SortByExtension},
+			expected: []string{"Zdir", "dir", "noext", "a.go", "B.md", "file10.txt", "file2.txt"},
+		},
+		{
+			name:     "Directories first",
+			opts:     BuildOptions{DirsFirst: true},
+			expected: []string{"Zdir", "dir", "B.md", "a.go", "file10.txt", "file2.txt", "noext"},
+		},
+		{
+			name:     "Files first by descending size",
+			opts:     BuildOptions{FilesFirst: true, SortBy: SortBySize, SortDescending: true},
+			expected: []string{"file2.txt", "B.md", "file10.txt", "a.go", "noext", "dir", "Zdir"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.MaxDepth, opts.IncludeFiles, opts.Concurrency = -1, true, 4
+			root, err := BuildTreeFS(fsys, opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var names []string
+			for _, child := range root.Children {
+				names = append(names, child.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Children = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package tree
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// sysStat extracts the platform specific status of info
+func sysStat(info fs.FileInfo) (sysInfo, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return sysInfo{}, false
+	}
+	return sysInfo{
+		dev:    uint64(st.Dev),
+		ino:    st.Ino,
+		nlink:  uint64(st.Nlink),
+		uid:    st.Uid,
+		gid:    st.Gid,
+		blocks: st.Blocks,
+		atime:  time.Unix(st.Atim.Unix()),
+		ctime:  time.Unix(st.Ctim.Unix()),
+	}, true
+}
+
+// fileID returns the device and inode number identifying info's file
+func fileID(info fs.FileInfo) (dev, ino uint64, ok bool) {
+	sys, ok := sysStat(info)
+	return sys.dev, sys.ino, ok
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package tree
+
+import "io/fs"
+
+// sysStat is not supported on this platform
+func sysStat(info fs.FileInfo) (sysInfo, bool) {
+	return sysInfo{}, false
+}
+
+// fileID is not supported on this platform; callers fall back to names
+func fileID(info fs.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
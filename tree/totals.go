@@ -0,0 +1,82 @@
+package tree
+
+import "io/fs"
+
+// hardLinks maps files with more than one hard link to their sizes. It is
+// only tracked while building, to count such files once per subtree.
+type hardLinks map[fileKey]linkedSize
+
+// linkedSize holds both size measures of a hard linked file
+type linkedSize struct {
+	size, usage int64
+}
+
+// hardLinksOf returns the hard link set for a single file node, or nil if
+// deduplication is disabled or the file has only one link
+func (b *builder) hardLinksOf(node *Node, info fs.FileInfo) hardLinks {
+	if !b.opts.DedupHardLinks || node.Type != File {
+		return nil
+	}
+	sys, ok := sysStat(info)
+	if !ok || sys.nlink < 2 {
+		return nil
+	}
+	return hardLinks{{dev: sys.dev, ino: sys.ino}: {size: node.Size, usage: node.DiskUsage}}
+}
+
+// diskUsage returns the space allocated for info's file, or 0 if the
+// platform does not report block counts
+func diskUsage(info fs.FileInfo) int64 {
+	sys, ok := sysStat(info)
+	if !ok {
+		return 0
+	}
+	return sys.blocks * 512
+}
+
+// sumChildren fills the aggregate fields of the directory node from its
+// children. links holds the hard link set of each child; files appearing in
+// more than one child are counted once. The merged set is returned.
+// Like du, the disk usage of a directory includes its own blocks.
+func sumChildren(node *Node, links []hardLinks) hardLinks {
+	node.Size = 0
+	node.FileCount = 0
+	node.DirCount = 0
+	node.MaxDepth = 0
+
+	var merged hardLinks
+	for i, child := range node.Children {
+		merged = addChild(node, child, links[i], merged)
+	}
+	return merged
+}
+
+// addChild adds the totals of child, whose hard link set is links, to the
+// directory node. merged is the set of the children added so far; files
+// already in it are not counted again. The updated set is returned.
+func addChild(node, child *Node, links, merged hardLinks) hardLinks {
+	node.Size += child.Size
+	node.DiskUsage += child.DiskUsage
+	if child.Type == Directory {
+		node.DirCount += 1 + child.DirCount
+		node.FileCount += child.FileCount
+		node.MaxDepth = max(node.MaxDepth, child.MaxDepth+1)
+	} else {
+		node.FileCount++
+		node.MaxDepth = max(node.MaxDepth, 1)
+	}
+
+	for key, size := range links {
+		if _, seen := merged[key]; seen {
+			node.Size -= size.size
+			node.DiskUsage -= size.usage
+			node.FileCount--
+			continue
+		}
+		if merged == nil {
+			merged = make(hardLinks)
+		}
+		merged[key] = size
+	}
+	return merged
+}
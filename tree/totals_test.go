@@ -0,0 +1,186 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+)
+
+// TestDirectoryTotals tests aggregated sizes and counts of directories
+func TestDirectoryTotals(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":            {Data: make([]byte, 10)},
+		"dir1/b.txt":       {Data: make([]byte, 20)},
+		"dir1/sub/c.txt":   {Data: make([]byte, 30)},
+		"dir1/sub/d.txt":   {Data: make([]byte, 40)},
+		"dir2/empty/.keep": {},
+	}
+
+	root, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		node      *Node
+		size      int64
+		fileCount int
+		dirCount  int
+		maxDepth  int
+	}{
+		{root, 100, 5, 4, 3},
+		{root.Children[1], 90, 3, 1, 2},
+		{root.Children[1].Children[1], 70, 2, 0, 1},
+		{root.Children[2], 0, 1, 1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.node.Path, func(t *testing.T) {
+			if tt.node.Size != tt.size {
+				t.Errorf("Size = %d, want %d", tt.node.Size, tt.size)
+			}
+			if tt.node.FileCount != tt.fileCount {
+				t.Errorf("FileCount = %d, want %d", tt.node.FileCount, tt.fileCount)
+			}
+			if tt.node.DirCount != tt.dirCount {
+				t.Errorf("DirCount = %d, want %d", tt.node.DirCount, tt.dirCount)
+			}
+			if tt.node.MaxDepth != tt.maxDepth {
+				t.Errorf("MaxDepth = %d, want %d", tt.node.MaxDepth, tt.maxDepth)
+			}
+		})
+	}
+}
+
+// TestMaxDepthTotals tests that, like du --max-depth, directory totals
+// include the nodes below the maximum depth left out of the tree
+func TestMaxDepthTotals(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":          {Data: make([]byte, 10)},
+		"dir1/b.txt":     {Data: make([]byte, 20)},
+		"dir1/sub/c.txt": {Data: make([]byte, 30)},
+		"dir1/sub/d.txt": {Data: make([]byte, 40)},
+	}
+
+	full, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	root, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: 1, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if root.Size != full.Size || root.FileCount != full.FileCount || root.DirCount != full.DirCount || root.MaxDepth != full.MaxDepth {
+		t.Errorf("Root totals = %d bytes, %d files, %d dirs, depth %d, want %d, %d, %d, %d",
+			root.Size, root.FileCount, root.DirCount, root.MaxDepth, full.Size, full.FileCount, full.DirCount, full.MaxDepth)
+	}
+	dir1 := root.Children[1]
+	if dir1.Size != 90 || dir1.FileCount != 3 || dir1.DirCount != 1 {
+		t.Errorf("dir1 totals = %d bytes, %d files, %d dirs, want 90, 3, 1", dir1.Size, dir1.FileCount, dir1.DirCount)
+	}
+	if len(dir1.Children) != 0 {
+		t.Errorf("dir1 has %d children beyond the maximum depth", len(dir1.Children))
+	}
+}
+
+// TestDedupHardLinks tests that hard linked files are counted once per subtree
+func TestDedupHardLinks(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("hard link detection requires inode numbers")
+	}
+
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+	original := filepath.Join(tmpDir, "a", "file")
+	if err := os.WriteFile(original, make([]byte, 100), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	for _, link := range []string{"a/link", "b/link"} {
+		if err := os.Link(original, filepath.Join(tmpDir, link)); err != nil {
+			t.Fatalf("Failed to create hard link: %v", err)
+		}
+	}
+
+	opts := BuildOptions{Path: tmpDir, MaxDepth: -1, IncludeFiles: true}
+
+	root, err := BuildTree(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if root.Size != 300 || root.FileCount != 3 {
+		t.Errorf("Without dedup: Size = %d, FileCount = %d, want 300, 3", root.Size, root.FileCount)
+	}
+
+	opts.DedupHardLinks = true
+	opts.Concurrency = 4
+	root, err = BuildTree(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if root.Size != 100 || root.FileCount != 1 {
+		t.Errorf("Root: Size = %d, FileCount = %d, want 100, 1", root.Size, root.FileCount)
+	}
+	if a := root.Children[0]; a.Size != 100 || a.FileCount != 1 {
+		t.Errorf("Dir a: Size = %d, FileCount = %d, want 100, 1", a.Size, a.FileCount)
+	}
+	if b := root.Children[1]; b.Size != 100 || b.FileCount != 1 {
+		t.Errorf("Dir b: Size = %d, FileCount = %d, want 100, 1", b.Size, b.FileCount)
+	}
+}
+
+// TestDiskUsage tests that sparse files report allocated rather than apparent size
+func TestDiskUsage(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("disk usage requires block counts")
+	}
+
+	tmpDir := t.TempDir()
+	sparse, err := os.Create(filepath.Join(tmpDir, "sparse.img"))
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	const apparent = 64 << 20
+	if err := sparse.Truncate(apparent); err != nil {
+		t.Fatalf("Failed to extend test file: %v", err)
+	}
+	sparse.Close()
+
+	const written = 64 << 10
+	if err := os.WriteFile(filepath.Join(tmpDir, "data.bin"), make([]byte, written), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	root, err := BuildTree(BuildOptions{Path: tmpDir, MaxDepth: -1, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, file := root.Children[0], root.Children[1]
+	if data.DiskUsage < written {
+		t.Errorf("DiskUsage of written file = %d, want at least %d", data.DiskUsage, written)
+	}
+	if file.Size != apparent {
+		t.Errorf("Size = %d, want %d", file.Size, apparent)
+	}
+	if file.DiskUsage >= apparent/2 {
+		t.Errorf("DiskUsage of sparse file = %d, expected far less than its apparent size %d", file.DiskUsage, apparent)
+	}
+	if root.Size != apparent+written || root.DiskUsage < data.DiskUsage+file.DiskUsage {
+		t.Errorf("Root totals: Size = %d, DiskUsage = %d", root.Size, root.DiskUsage)
+	}
+
+	root, err = BuildTree(BuildOptions{Path: tmpDir, MaxDepth: -1, IncludeFiles: true, SortBy: SortByDiskUsage})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name := root.Children[0].Name; name != "sparse.img" {
+		t.Errorf("First child by disk usage = %s, want sparse.img", name)
+	}
+}
@@ -1,11 +1,17 @@
 package tree
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // FileType represents the type of a file system node
@@ -25,36 +31,346 @@ type Node struct {
 	Size     int64    `json:"size,omitempty"`
 	Children []*Node  `json:"children,omitempty"`
 	IsHidden bool     `json:"is_hidden,omitempty"`
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty" xml:"error,omitempty"`    // Why the node could not be read, see RecordOnError
+	Target   string   `json:"target,omitempty" yaml:"target,omitempty" xml:"target,omitempty"` // Resolved path of a symbolic link
+	Cycle    bool     `json:"cycle,omitempty" yaml:"cycle,omitempty" xml:"cycle,omitempty"`    // Followed link leads back to an ancestor directory
+	Hash     string   `json:"hash,omitempty" yaml:"hash,omitempty" xml:"hash,omitempty"`       // Content digest of a file as "algorithm:hex", see BuildOptions.Hash
+
+	// DiskUsage is the space allocated on disk (st_blocks), which differs
+	// from the apparent Size for sparse and compressed files. It is only
+	// available on Linux.
+	DiskUsage int64 `json:"disk_usage,omitempty" yaml:"disk_usage,omitempty" xml:"disk_usage,omitempty"`
+
+	// Directory totals over all descendants in the tree. Size of a
+	// directory is the total size of its descendants.
+	FileCount int `json:"file_count,omitempty" yaml:"file_count,omitempty" xml:"file_count,omitempty"` // Number of non-directory descendants
+	DirCount  int `json:"dir_count,omitempty" yaml:"dir_count,omitempty" xml:"dir_count,omitempty"`    // Number of directory descendants
+	MaxDepth  int `json:"max_depth,omitempty" yaml:"max_depth,omitempty" xml:"max_depth,omitempty"`    // Number of levels below the directory
+
+	// Metadata, only set when BuildOptions.CollectMetadata is enabled.
+	// Fields that the platform cannot provide are left empty.
+	ModTime    *time.Time `json:"mod_time,omitempty" yaml:"mod_time,omitempty" xml:"mod_time,omitempty"`
+	AccessTime *time.Time `json:"access_time,omitempty" yaml:"access_time,omitempty" xml:"access_time,omitempty"`
+	ChangeTime *time.Time `json:"change_time,omitempty" yaml:"change_time,omitempty" xml:"change_time,omitempty"`
+	Mode       string     `json:"mode,omitempty" yaml:"mode,omitempty" xml:"mode,omitempty"`                      // Permission string as shown by ls -l, e.g. "drwxr-xr-x"
+	Perm       string     `json:"perm,omitempty" yaml:"perm,omitempty" xml:"perm,omitempty"`                      // Octal permission bits, e.g. "0755"
+	UID        *uint32    `json:"uid,omitempty" yaml:"uid,omitempty" xml:"uid,omitempty"`                         // Owner user id
+	GID        *uint32    `json:"gid,omitempty" yaml:"gid,omitempty" xml:"gid,omitempty"`                         // Owner group id
+	Owner      string     `json:"owner,omitempty" yaml:"owner,omitempty" xml:"owner,omitempty"`                   // Owner user name
+	Group      string     `json:"group,omitempty" yaml:"group,omitempty" xml:"group,omitempty"`                   // Owner group name
+	Inode      uint64     `json:"inode,omitempty" yaml:"inode,omitempty" xml:"inode,omitempty"`                   // Inode number
+	Device     uint64     `json:"device,omitempty" yaml:"device,omitempty" xml:"device,omitempty"`                // Device the file resides on
+	Links      uint64     `json:"links,omitempty" yaml:"links,omitempty" xml:"links,omitempty"`                   // Number of hard links
+	LinkTarget string     `json:"link_target,omitempty" yaml:"link_target,omitempty" xml:"link_target,omitempty"` // Symbolic link contents as stored on disk
 }
+
+// BuildOptions controls how BuildTree walks the file system
 type BuildOptions struct {
 	Path         string
-	MaxDepth     int
+	MaxDepth     int // -1 for unlimited; deeper nodes still count towards directory totals, like du --max-depth
 	ExcludePaths []string
 	ExcludeTypes []string
 	IncludeFiles bool
 	FollowLinks  bool
+	// Exclude skips every path matching one of the patterns. Unlike
+	// ExcludePaths, patterns are matched relative to the scan root.
+	Exclude []Pattern
+	// Include, when not empty, keeps only files matching one of the
+	// patterns. Directories are always traversed.
+	Include []Pattern
+	// Filter, when set, keeps only nodes matching the expression, along
+	// with the directories leading to them
+	Filter *Filter
+	// ConfineLinks prevents FollowLinks from descending into link targets
+	// outside the scan root. Such links are kept as symlink leaves.
+	ConfineLinks bool
+	// DedupHardLinks counts files with several hard links only once in
+	// directory totals
+	DedupHardLinks bool
+	// GitIgnore skips paths ignored by .gitignore files, including nested
+	// ones and those of an enclosing repository, and by .git/info/exclude.
+	// The .git directory itself is skipped as well.
+	GitIgnore bool
+	// DirtreeIgnore skips paths matched by .dirtreeignore files, which use
+	// the .gitignore syntax and take precedence over .gitignore
+	DirtreeIgnore bool
+	// CollectMetadata fills the metadata fields of Node (times, mode,
+	// ownership, inode and link target)
+	CollectMetadata bool
+	// Hash, when set, reads every file to fill Node.Hash with a digest of
+	// its contents
+	Hash HashAlgorithm
+	// SortBy orders the children of each directory; the default keeps the
+	// order entries are listed in, which is by name for os.ReadDir and
+	// fs.ReadDir. Ties are broken by name.
+	SortBy SortKey
+	// SortDescending reverses the order selected by SortBy
+	SortDescending bool
+	// DirsFirst lists directories before other nodes, FilesFirst after
+	DirsFirst  bool
+	FilesFirst bool
+	// ErrorPolicy selects what happens when a file or directory cannot be
+	// read. The default aborts the scan; with SkipOnError and RecordOnError
+	// the failures are returned in a *PartialError along with the tree.
+	ErrorPolicy ErrorPolicy
+	// Concurrency is the maximum number of goroutines reading directories
+	// at the same time. Values below 2 scan serially. The resulting tree
+	// does not depend on this setting.
+	Concurrency int
 }
 
 // BuildTree constructs a directory tree from the given options
 func BuildTree(opts BuildOptions) (*Node, error) {
-	info, err := os.Stat(opts.Path)
+	return BuildTreeContext(context.Background(), opts)
+}
+
+// BuildTreeContext constructs a directory tree like BuildTree, but stops
+// reading directories once ctx is done. In that case the partially built
+// tree is returned together with a *PartialError wrapping ctx.Err().
+// A *PartialError is also returned when paths could not be read and
+// opts.ErrorPolicy tolerates it.
+func BuildTreeContext(ctx context.Context, opts BuildOptions) (*Node, error) {
+	return buildTree(ctx, osFS{}, &opts)
+}
+
+// BuildTreeFS constructs a directory tree from fsys. opts.Path names the
+// root inside fsys and defaults to ".". Symbolic links are only recognised
+// and followed when fsys implements LstatFS and ReadLinkFS.
+func BuildTreeFS(fsys fs.FS, opts BuildOptions) (*Node, error) {
+	return BuildTreeFSContext(context.Background(), fsys, opts)
+}
+
+// BuildTreeFSContext is the context-aware variant of BuildTreeFS
+func BuildTreeFSContext(ctx context.Context, fsys fs.FS, opts BuildOptions) (*Node, error) {
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if !fs.ValidPath(opts.Path) {
+		return nil, fmt.Errorf("error accessing path %s: %w", opts.Path, fs.ErrInvalid)
+	}
+	return buildTree(ctx, fsys, &opts)
+}
+
+// buildTree builds the tree rooted at opts.Path in fsys
+func buildTree(ctx context.Context, fsys fs.FS, opts *BuildOptions) (*Node, error) {
+	info, err := fs.Stat(fsys, opts.Path)
 	if err != nil {
 		return nil, fmt.Errorf("error accessing path %s: %w", opts.Path, err)
 	}
 
-	return buildTreeRecursive(opts.Path, info, &opts, 0)
+	excludes, err := compilePatterns(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	includes, err := compilePatterns(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBuilder(ctx, fsys, opts)
+	b.excludes, b.includes = excludes, includes
+	defer b.cancel()
+
+	ignores, ignoreBase := b.rootIgnores()
+	root, _, err := b.buildTreeRecursive(visit{
+		name:       opts.Path,
+		path:       opts.Path,
+		rel:        ".",
+		ignoreBase: ignoreBase,
+		ignores:    ignores,
+	}, info)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b.unscanned) > 0 || len(b.failures) > 0 {
+		sort.Strings(b.unscanned)
+		sort.Slice(b.failures, func(i, j int) bool { return b.failures[i].Path < b.failures[j].Path })
+		return root, &PartialError{Err: ctx.Err(), Unscanned: b.unscanned, Failures: b.failures}
+	}
+	return root, nil
 }
 
-// buildTreeRecursive recursively builds the directory tree
-func buildTreeRecursive(currentPath string, info os.FileInfo, opts *BuildOptions, currentDepth int) (*Node, error) {
-	// Check depth limit
-	if opts.MaxDepth != -1 && currentDepth > opts.MaxDepth {
-		return nil, nil
+// builder holds the state shared by all goroutines of a single BuildTree call
+type builder struct {
+	opts   *BuildOptions
+	fsys   fs.FS
+	native bool   // fsys uses OS paths instead of slash separated names
+	root   string // resolved scan root, used to confine followed links
+	ctx    context.Context
+	cancel context.CancelFunc // stops the remaining workers after a failure
+	sem    chan struct{}      // free worker slots, nil when scanning serially
+
+	names nameCache // user and group names for metadata
+
+	excludes []func(rel string) bool // compiled BuildOptions.Exclude
+	includes []func(rel string) bool // compiled BuildOptions.Include
+	now      time.Time               // reference time for Filter age tests
+
+	mu        sync.Mutex
+	unscanned []string     // directories skipped because ctx was done
+	failures  []*ScanError // paths that could not be read, see ErrorPolicy
+}
+
+// newBuilder creates a builder for the given options
+func newBuilder(ctx context.Context, fsys fs.FS, opts *BuildOptions) *builder {
+	b := &builder{opts: opts, fsys: fsys, native: isNative(fsys), root: opts.Path, now: time.Now()}
+	b.ctx, b.cancel = context.WithCancel(ctx)
+	if root, err := evalSymlinks(fsys, opts.Path); err == nil {
+		b.root = root
+	}
+	if b.native {
+		if abs, err := filepath.Abs(b.root); err == nil {
+			b.root = abs
+		}
+	}
+	if opts.Concurrency > 1 {
+		// The calling goroutine does work too, so it needs no slot
+		b.sem = make(chan struct{}, opts.Concurrency-1)
+	}
+	return b
+}
+
+// tryAcquire reserves a worker slot without blocking
+func (b *builder) tryAcquire() bool {
+	if b.sem == nil {
+		return false
+	}
+	select {
+	case b.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a worker slot reserved by tryAcquire
+func (b *builder) release() {
+	<-b.sem
+}
+
+// markUnscanned records a directory whose contents were not read
+func (b *builder) markUnscanned(path string) {
+	b.mu.Lock()
+	b.unscanned = append(b.unscanned, path)
+	b.mu.Unlock()
+}
+
+// join joins name elements the way fsys expects
+func (b *builder) join(elem ...string) string {
+	if b.native {
+		return filepath.Join(elem...)
 	}
+	return path.Join(elem...)
+}
+
+// resolveLink returns the final target of the symbolic link name
+func (b *builder) resolveLink(name string) (string, fs.FileInfo, error) {
+	target, err := evalSymlinks(b.fsys, name)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := fs.Stat(b.fsys, target)
+	if err != nil {
+		return "", nil, err
+	}
+	return target, info, nil
+}
+
+// mayFollow reports whether a link to target may be followed
+func (b *builder) mayFollow(target string) bool {
+	if !b.opts.ConfineLinks {
+		return true
+	}
+	if b.native {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return false
+		}
+		rel, err := filepath.Rel(b.root, abs)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	return b.root == "." || target == b.root || strings.HasPrefix(target, b.root+"/")
+}
+
+// dirKey identifies a directory for cycle detection
+func (b *builder) dirKey(name string, info fs.FileInfo) fileKey {
+	if dev, ino, ok := fileID(info); ok {
+		return fileKey{dev: dev, ino: ino}
+	}
+	return fileKey{name: name}
+}
+
+// fileKey identifies a file by device and inode, or by its resolved name
+// where the platform or file system does not expose inodes
+type fileKey struct {
+	dev, ino uint64
+	name     string
+}
+
+// ancestor is a link in the chain of directories above the current node
+type ancestor struct {
+	key    fileKey
+	parent *ancestor
+}
+
+// contains reports whether key belongs to a directory in the chain
+func (a *ancestor) contains(key fileKey) bool {
+	for ; a != nil; a = a.parent {
+		if a.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// visit describes where in the traversal a node is built
+type visit struct {
+	name       string       // name used to access the file in fsys
+	path       string       // path reported on the node
+	rel        string       // slash separated path relative to the scan root
+	ignoreBase string       // slash separated path relative to the ignore root
+	depth      int          // depth below the scan root
+	parents    *ancestor    // directories above the node
+	ignores    *ignoreStack // ignore rules applying to the node
+}
+
+// child returns the visit for the entry called base inside the directory v
+func (b *builder) child(v visit, base string) visit {
+	return visit{
+		name:       b.join(v.name, base),
+		path:       b.join(v.path, base),
+		rel:        path.Join(v.rel, base),
+		ignoreBase: path.Join(v.ignoreBase, base),
+		depth:      v.depth + 1,
+		parents:    v.parents,
+		ignores:    v.ignores,
+	}
+}
+
+// candidate is a node that passed every check not requiring its children
+type candidate struct {
+	v       visit
+	node    *Node
+	name    string      // name used to read a directory, the target of followed links
+	info    fs.FileInfo // status of the node, or of the target of followed links
+	matched bool        // whether the node matches opts.Filter
+}
+
+// prepare creates the node visited by v and applies the exclusion checks
+// and the filter. It returns nil if the node is left out of the tree;
+// directories that do not match the filter are only dropped later, if
+// nothing below them matches.
+func (b *builder) prepare(v visit, info fs.FileInfo) *candidate {
+	opts := b.opts
+	name, currentPath, currentDepth := v.name, v.path, v.depth
 
 	// Check path exclusions
 	if isExcludedPath(currentPath, opts.ExcludePaths) {
-		return nil, nil
+		return nil
+	}
+	if currentDepth > 0 && matchesAny(v.rel, b.excludes) {
+		return nil
 	}
 
 	node := &Node{
@@ -62,90 +378,295 @@ func buildTreeRecursive(currentPath string, info os.FileInfo, opts *BuildOptions
 		Path: currentPath,
 	}
 
+	// Resolve symlinks, and continue with the target if following is enabled
+	linkName := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		linkName = name
+		target, targetInfo, err := b.resolveLink(name)
+		if err == nil {
+			node.Target = target
+			if opts.FollowLinks && b.mayFollow(target) {
+				name, info = target, targetInfo
+			}
+		}
+	}
+
 	// Determine node type and set size
 	if info.IsDir() {
 		node.Type = Directory
-		node.Size = 0 // Directories have size 0 or could calculate total size
-	} else if info.Mode()&os.ModeSymlink != 0 {
+		node.Size = 0 // Directories get the total size of their children below
+	} else if info.Mode()&fs.ModeSymlink != 0 {
 		node.Type = Symlink
 		node.Size = info.Size()
-
-		// Handle symlinks if following is enabled
-		if opts.FollowLinks {
-			targetPath, err := filepath.EvalSymlinks(currentPath)
-			if err == nil {
-				targetInfo, err := os.Stat(targetPath)
-				if err == nil {
-					if targetInfo.IsDir() {
-						node.Type = Directory
-						node.Size = 0
-					} else {
-						node.Type = File
-						node.Size = targetInfo.Size()
-					}
-				}
-			}
-		}
 	} else {
 		node.Type = File
 		node.Size = info.Size()
 	}
+	node.DiskUsage = diskUsage(info)
+
+	// Skip files if not included
+	if currentDepth > 0 && !opts.IncludeFiles && node.Type != Directory {
+		return nil
+	}
 
 	// Check type exclusions
 	if node.Type == File && isExcludedType(currentPath, opts.ExcludeTypes) {
-		return nil, nil
+		return nil
+	}
+
+	// Check include patterns
+	if currentDepth > 0 && node.Type != Directory && len(b.includes) > 0 && !matchesAny(v.rel, b.includes) {
+		return nil
+	}
+
+	// Check ignore files
+	if currentDepth > 0 && b.isIgnored(v, node.Type == Directory) {
+		return nil
 	}
 
 	// Check if file is hidden
-	node.IsHidden = isHiddenFile(info.Name())
+	node.IsHidden = isHiddenFile(node.Name)
+
+	// Evaluate the filter; directories that do not match are still
+	// traversed and only dropped if nothing below them matches
+	matched := currentDepth == 0 || b.matchesFilter(v, node, info)
+	if !matched && node.Type != Directory {
+		return nil
+	}
+
+	if opts.CollectMetadata && !b.beyondDepth(currentDepth) {
+		b.fillMetadata(node, info, linkName)
+	}
+
+	return &candidate{v: v, node: node, name: name, info: info, matched: matched}
+}
+
+// buildTreeRecursive recursively builds the directory tree. v.name is used
+// to access the file in fsys, while v.path is reported on the node; they
+// differ below followed symbolic links. The hard links found in the subtree
+// are returned for deduplication by the parent.
+func (b *builder) buildTreeRecursive(v visit, info fs.FileInfo) (*Node, hardLinks, error) {
+	c := b.prepare(v, info)
+	if c == nil {
+		return nil, nil, nil
+	}
+	node := c.node
 
 	// If directory (or symlink to directory with followLinks), process children
 	if node.Type == Directory {
-		var entries []os.DirEntry
-		var err error
-
-		// Get directory entries
-		if opts.FollowLinks && node.Type == Symlink {
-			// For symlinks, get contents of target directory
-			targetPath, err := filepath.EvalSymlinks(currentPath)
-			if err == nil {
-				entries, err = os.ReadDir(targetPath)
-				if err != nil {
-					return nil, fmt.Errorf("error reading directory %s: %w", targetPath, err)
-				}
-			}
-		} else {
-			entries, err = os.ReadDir(currentPath)
+		entries, key, leaf, err := b.openDir(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		if leaf {
+			return node, nil, nil
+		}
+		if entries == nil {
+			return nil, nil, nil
 		}
 
+		v := c.v
+		v.name = c.name
+		v.parents = &ancestor{key: key, parent: v.parents}
+		v.ignores = b.loadIgnoreRules(v.ignores, c.name, v.ignoreBase)
+		children, links, err := b.buildChildren(v, entries)
 		if err != nil {
-			return nil, fmt.Errorf("error reading directory %s: %w", currentPath, err)
+			return nil, nil, err
 		}
+		if !c.matched && len(children) == 0 {
+			return nil, nil, nil
+		}
+		node.Children = children
+		merged := sumChildren(node, links)
+		if b.beyondDepth(v.depth + 1) {
+			node.Children = nil
+		}
+		return node, merged, nil
+	}
 
-		for _, entry := range entries {
-			entryInfo, err := entry.Info()
-			if err != nil {
-				continue // Skip problematic entries
-			}
+	if b.opts.Hash != "" && node.Type == File && !b.beyondDepth(v.depth) {
+		if kept, err := b.hashFile(c); kept == nil {
+			return nil, nil, err
+		}
+	}
+	return node, b.hardLinksOf(node, c.info), nil
+}
 
-			fullPath := filepath.Join(currentPath, entryInfo.Name())
+// beyondDepth reports whether nodes at depth are below opts.MaxDepth. Like
+// du --max-depth, they are scanned for the totals of the directories
+// above them, but left out of the tree.
+func (b *builder) beyondDepth(depth int) bool {
+	return b.opts.MaxDepth != -1 && depth > b.opts.MaxDepth
+}
 
-			// Skip files if not included
-			if !opts.IncludeFiles && !entryInfo.IsDir() {
-				continue
-			}
+// openDir reads the entries of the directory candidate c. It reports
+// leaf if the directory is kept without reading its children: when it
+// loops back to an ancestor, the scan was cancelled or, under the record
+// error policy, it could not be read. It returns no entries and no error
+// if the directory is dropped instead.
+func (b *builder) openDir(c *candidate) (entries []fs.DirEntry, key fileKey, leaf bool, err error) {
+	node := c.node
 
-			child, err := buildTreeRecursive(fullPath, entryInfo, opts, currentDepth+1)
-			if err != nil {
-				return nil, err
-			}
-			if child != nil {
-				node.Children = append(node.Children, child)
+	// A directory that is its own ancestor is only reachable through a
+	// followed link; keep it as a leaf instead of recursing forever
+	key = b.dirKey(c.name, c.info)
+	if c.v.parents.contains(key) {
+		if !c.matched {
+			return nil, key, false, nil
+		}
+		node.Cycle = true
+		return nil, key, true, nil
+	}
+
+	// Leave the directory empty once the scan has been cancelled
+	if b.ctx.Err() != nil {
+		b.markUnscanned(node.Path)
+		return nil, key, true, nil
+	}
+
+	entries, err = fs.ReadDir(b.fsys, c.name)
+	if err != nil {
+		if !b.opts.ErrorPolicy.tolerant() {
+			return nil, key, false, fmt.Errorf("error reading directory %s: %w", node.Path, err)
+		}
+		return nil, key, b.recordFailure(node, err, c.v.depth) != nil, nil
+	}
+	if entries == nil {
+		entries = []fs.DirEntry{}
+	}
+	return entries, key, false, nil
+}
+
+// buildChildren builds the nodes for the entries of a directory.
+// Subdirectories are handed to idle workers when available, but children
+// are always returned in entry order, along with their hard link sets.
+func (b *builder) buildChildren(dir visit, entries []fs.DirEntry) ([]*Node, []hardLinks, error) {
+	results := make([]*Node, len(entries))
+	modTimes := make([]time.Time, len(entries))
+	sets := make([]hardLinks, len(entries))
+	errs := make([]error, len(entries))
+	var wg sync.WaitGroup
+
+	for i, entry := range entries {
+		entryInfo, err := entry.Info()
+		if err != nil {
+			results[i], errs[i] = b.entryFailed(dir, entry, err)
+			if errs[i] != nil {
+				b.cancel()
+				break
 			}
+			continue
+		}
+
+		v := b.child(dir, entryInfo.Name())
+		modTimes[i] = entryInfo.ModTime()
+
+		// Skip files if not included
+		isLink := entryInfo.Mode()&fs.ModeSymlink != 0
+		if !b.opts.IncludeFiles && !entryInfo.IsDir() && !(isLink && b.opts.FollowLinks) {
+			continue
+		}
+
+		if (entryInfo.IsDir() || isLink && b.opts.FollowLinks) && b.tryAcquire() {
+			wg.Add(1)
+			go func(i int, v visit, entryInfo fs.FileInfo) {
+				defer wg.Done()
+				defer b.release()
+				results[i], sets[i], errs[i] = b.buildTreeRecursive(v, entryInfo)
+				if errs[i] != nil {
+					b.cancel()
+				}
+			}(i, v, entryInfo)
+			continue
+		}
+
+		results[i], sets[i], errs[i] = b.buildTreeRecursive(v, entryInfo)
+		if errs[i] != nil {
+			b.cancel()
+			break
+		}
+	}
+	wg.Wait()
+
+	var sorted []sortEntry
+	for i, child := range results {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		if child != nil {
+			sorted = append(sorted, sortEntry{node: child, links: sets[i], modTime: modTimes[i]})
 		}
 	}
+	sortChildren(sorted, b.opts)
 
-	return node, nil
+	var children []*Node
+	var links []hardLinks
+	for _, entry := range sorted {
+		children = append(children, entry.node)
+		links = append(links, entry.links)
+	}
+	return children, links, nil
+}
+
+// matchesFilter checks whether the node visited by v matches opts.Filter
+func (b *builder) matchesFilter(v visit, node *Node, info fs.FileInfo) bool {
+	if b.opts.Filter == nil {
+		return true
+	}
+	return b.opts.Filter.match(&filterEntry{
+		name:  node.Name,
+		rel:   v.rel,
+		typ:   node.Type,
+		info:  info,
+		now:   b.now,
+		names: &b.names,
+	})
+}
+
+// entryFailed handles a directory entry whose status could not be read.
+// Entries removed since the directory was listed are skipped silently.
+func (b *builder) entryFailed(dir visit, entry fs.DirEntry, err error) (*Node, error) {
+	v := b.child(dir, entry.Name())
+	if errors.Is(err, fs.ErrNotExist) || (!b.opts.IncludeFiles && !entry.IsDir()) ||
+		isExcludedPath(v.path, b.opts.ExcludePaths) || matchesAny(v.rel, b.excludes) {
+		return nil, nil
+	}
+	if !b.opts.ErrorPolicy.tolerant() {
+		return nil, fmt.Errorf("error reading %s: %w", v.path, err)
+	}
+
+	node := &Node{Name: entry.Name(), Path: v.path, Type: File}
+	switch {
+	case entry.IsDir():
+		node.Type = Directory
+	case entry.Type()&fs.ModeSymlink != 0:
+		node.Type = Symlink
+	}
+	node.IsHidden = isHiddenFile(node.Name)
+	return b.recordFailure(node, err, v.depth), nil
+}
+
+// recordFailure collects a read error under the skip and record policies.
+// It returns the node to keep in the tree, or nil if it is skipped; the
+// root is always kept.
+func (b *builder) recordFailure(node *Node, err error, depth int) *Node {
+	b.mu.Lock()
+	b.failures = append(b.failures, &ScanError{Path: node.Path, Err: err})
+	b.mu.Unlock()
+
+	if b.opts.ErrorPolicy == SkipOnError && depth > 0 {
+		return nil
+	}
+	node.Error = err.Error()
+	return node
+}
+
+// isIgnored checks whether the node visited by v is excluded by ignore files
+func (b *builder) isIgnored(v visit, isDir bool) bool {
+	if b.opts.GitIgnore && isDir && path.Base(v.ignoreBase) == ".git" {
+		return true
+	}
+	return v.ignores.ignored(v.ignoreBase, isDir)
 }
 
 // isExcludedPath checks if a path matches any exclusion patterns
@@ -1,9 +1,14 @@
 package tree
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"testing/fstest"
 )
 
 // TestIsHiddenFile tests the isHiddenFile function
@@ -326,3 +331,285 @@ func TestBuildTreeError(t *testing.T) {
 		})
 	}
 }
+
+// TestBuildTreeConcurrency tests that parallel scanning yields the same tree
+func TestBuildTreeConcurrency(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			dir := filepath.Join(tmpDir, fmt.Sprintf("dir%d", i), fmt.Sprintf("sub%d", j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("Failed to create test directory: %v", err)
+			}
+			for k := 0; k < 3; k++ {
+				name := filepath.Join(dir, fmt.Sprintf("file%d.txt", k))
+				if err := os.WriteFile(name, []byte("data"), 0644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+			}
+		}
+	}
+
+	opts := BuildOptions{
+		Path:         tmpDir,
+		MaxDepth:     -1,
+		IncludeFiles: true,
+	}
+
+	expected, err := BuildTree(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, concurrency := range []int{2, 4, 16} {
+		t.Run(fmt.Sprintf("Concurrency %d", concurrency), func(t *testing.T) {
+			opts.Concurrency = concurrency
+			result, err := BuildTree(opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Error("Parallel scan produced a different tree than the serial scan")
+			}
+		})
+	}
+}
+
+// TestBuildTreeContextCancelled tests that a cancelled scan returns partial results
+func TestBuildTreeContextCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "dir1"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	root, err := BuildTreeContext(ctx, BuildOptions{
+		Path:         tmpDir,
+		MaxDepth:     -1,
+		IncludeFiles: true,
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	var partialErr *PartialError
+	if !errors.As(err, &partialErr) {
+		t.Fatalf("Expected *PartialError, got %T", err)
+	}
+	if len(partialErr.Unscanned) != 1 || partialErr.Unscanned[0] != tmpDir {
+		t.Errorf("Unscanned = %v, want [%s]", partialErr.Unscanned, tmpDir)
+	}
+
+	if root == nil {
+		t.Fatal("Expected partial root node")
+	}
+	if len(root.Children) != 0 {
+		t.Errorf("Expected no children after cancellation, got %d", len(root.Children))
+	}
+}
+
+// TestBuildTreeFS tests building a tree from an in-memory file system
+func TestBuildTreeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file1.txt":             {Data: []byte("hello")},
+		"file2.go":              {Data: []byte("package main")},
+		"dir1/file3.txt":        {},
+		"dir1/subdir1/file4.go": {},
+		"dir2/file5.txt":        {},
+		"dir2/.hidden":          {},
+	}
+
+	root, err := BuildTreeFS(fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if root.Path != "." || root.Type != Directory {
+		t.Fatalf("Unexpected root node: %+v", root)
+	}
+
+	names := []string{}
+	for _, child := range root.Children {
+		names = append(names, child.Name)
+	}
+	expected := []string{"dir1", "dir2", "file1.txt", "file2.go"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Root children = %v, want %v", names, expected)
+	}
+
+	file1 := root.Children[2]
+	if file1.Path != "file1.txt" || file1.Size != 5 {
+		t.Errorf("Unexpected file node: %+v", file1)
+	}
+
+	dir2 := root.Children[1]
+	if len(dir2.Children) != 2 || !dir2.Children[0].IsHidden || dir2.Children[0].Path != "dir2/.hidden" {
+		t.Errorf("Unexpected dir2 children: %+v", dir2.Children)
+	}
+
+	sub, err := BuildTreeFS(fsys, BuildOptions{Path: "dir1", MaxDepth: -1, IncludeFiles: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sub.Name != "dir1" || sub.Children[1].Children[0].Path != "dir1/subdir1/file4.go" {
+		t.Errorf("Unexpected subtree: %+v", sub)
+	}
+
+	if _, err := BuildTreeFS(fsys, BuildOptions{Path: "../outside"}); err == nil {
+		t.Error("Expected error for invalid path")
+	}
+}
+
+// TestDirFS tests that DirFS produces the same structure as BuildTree
+func TestDirFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "dir1", "subdir1"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "dir1", "file.txt"), []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	opts := BuildOptions{MaxDepth: -1, IncludeFiles: true}
+
+	fromFS, err := BuildTreeFS(DirFS(tmpDir), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	opts.Path = tmpDir
+	fromOS, err := BuildTree(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Paths differ by the root prefix only
+	var strip func(*Node, string)
+	strip = func(node *Node, prefix string) {
+		rel, err := filepath.Rel(prefix, node.Path)
+		if err != nil {
+			t.Fatalf("Failed to relativize %s: %v", node.Path, err)
+		}
+		node.Path = filepath.ToSlash(rel)
+		for _, child := range node.Children {
+			strip(child, prefix)
+		}
+	}
+	strip(fromOS, tmpDir)
+
+	if !reflect.DeepEqual(fromFS, fromOS) {
+		t.Error("BuildTreeFS(DirFS) and BuildTree produced different trees")
+	}
+}
+
+// TestBuildTreeFollowLinks tests symlink following, cycle detection and confinement
+func TestBuildTreeFollowLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(tmpDir, "a"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "a", "file.txt"), []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "external.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	links := map[string]string{
+		"a/loop": "..",
+		"b":      "a",
+		"ext":    outside,
+		"broken": "missing",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(tmpDir, link)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	find := func(node *Node, name string) *Node {
+		for _, child := range node.Children {
+			if child.Name == name {
+				return child
+			}
+		}
+		t.Fatalf("Node %s not found in %s", name, node.Path)
+		return nil
+	}
+
+	t.Run("Follow links", func(t *testing.T) {
+		root, err := BuildTree(BuildOptions{Path: tmpDir, MaxDepth: -1, IncludeFiles: true, FollowLinks: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		b := find(root, "b")
+		if b.Type != Directory || len(b.Children) != 2 {
+			t.Errorf("Link b should be followed into a directory with 2 children: %+v", b)
+		}
+		if b.Target != filepath.Join(tmpDirReal(t, tmpDir), "a") {
+			t.Errorf("b.Target = %s, want resolved path of a", b.Target)
+		}
+		if file := find(b, "file.txt"); file.Path != filepath.Join(tmpDir, "b", "file.txt") {
+			t.Errorf("Child of followed link has path %s", file.Path)
+		}
+
+		for _, dir := range []*Node{find(root, "a"), b} {
+			loop := find(dir, "loop")
+			if !loop.Cycle || loop.Type != Directory || len(loop.Children) != 0 {
+				t.Errorf("Loop link in %s should be a cycle leaf: %+v", dir.Name, loop)
+			}
+		}
+
+		if ext := find(root, "ext"); ext.Type != Directory || len(ext.Children) != 1 {
+			t.Errorf("Link ext should be followed outside the root: %+v", ext)
+		}
+
+		if broken := find(root, "broken"); broken.Type != Symlink || broken.Target != "" {
+			t.Errorf("Broken link should stay an unresolved symlink: %+v", broken)
+		}
+	})
+
+	t.Run("Confine links", func(t *testing.T) {
+		root, err := BuildTree(BuildOptions{Path: tmpDir, MaxDepth: -1, IncludeFiles: true, FollowLinks: true, ConfineLinks: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ext := find(root, "ext")
+		if ext.Type != Symlink || len(ext.Children) != 0 || ext.Target == "" {
+			t.Errorf("Link ext escapes the root and should not be followed: %+v", ext)
+		}
+		if b := find(root, "b"); b.Type != Directory {
+			t.Errorf("Link b stays inside the root and should be followed: %+v", b)
+		}
+	})
+
+	t.Run("Do not follow links", func(t *testing.T) {
+		root, err := BuildTree(BuildOptions{Path: tmpDir, MaxDepth: -1, IncludeFiles: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		b := find(root, "b")
+		if b.Type != Symlink || len(b.Children) != 0 || b.Target == "" {
+			t.Errorf("Link b should be a symlink leaf with a target: %+v", b)
+		}
+	})
+}
+
+// tmpDirReal returns the fully resolved path of dir
+func tmpDirReal(t *testing.T, dir string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", dir, err)
+	}
+	return resolved
+}
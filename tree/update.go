@@ -0,0 +1,239 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Update rescans the path rel, slash separated and relative to the root
+// of a tree built by BuildTree with the same opts, and puts the result in
+// place in root: the node is added, replaced or removed, and the totals
+// and order of the directories above it are brought up to date. Exclusions,
+// ignore files, the filter and MaxDepth apply as if the whole tree had been
+// scanned again, so changes to excluded paths leave the tree untouched.
+// Changing an ignore file rescans its directory.
+//
+// The changes made are returned as Diff would report them. With
+// DedupHardLinks the totals of the directories above rel no longer
+// account for hard links shared with the rest of the tree.
+func Update(ctx context.Context, root *Node, opts BuildOptions, rel string) ([]Change, error) {
+	return update(ctx, osFS{}, root, &opts, rel)
+}
+
+// UpdateFS updates a tree built by BuildTreeFS, see Update
+func UpdateFS(ctx context.Context, fsys fs.FS, root *Node, opts BuildOptions, rel string) ([]Change, error) {
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if !fs.ValidPath(opts.Path) {
+		return nil, fmt.Errorf("error accessing path %s: %w", opts.Path, fs.ErrInvalid)
+	}
+	return update(ctx, fsys, root, &opts, rel)
+}
+
+// scannedDir is a directory on the path from the root to an updated node
+type scannedDir struct {
+	node    *Node
+	name    string // name used to read the directory in fsys
+	matched bool   // whether the directory matches opts.Filter
+	usage   int64  // disk usage of the directory itself, without its children
+}
+
+// update implements Update for the tree rooted at opts.Path in fsys
+func update(ctx context.Context, fsys fs.FS, root *Node, opts *BuildOptions, rel string) ([]Change, error) {
+	rel = path.Clean(rel)
+	if !fs.ValidPath(rel) {
+		return nil, fmt.Errorf("error updating %s: %w", rel, fs.ErrInvalid)
+	}
+	if base := path.Base(rel); (opts.GitIgnore && base == gitIgnoreFile) || (opts.DirtreeIgnore && base == dirtreeIgnoreFile) {
+		rel = path.Dir(rel)
+	}
+	// Paths below the maximum depth only count towards the totals of the
+	// deepest directory shown, so that directory is rescanned instead
+	if parts := strings.Split(rel, "/"); opts.MaxDepth != -1 && rel != "." && len(parts) > opts.MaxDepth {
+		rel = path.Join(append([]string{"."}, parts[:opts.MaxDepth]...)...)
+	}
+	if rel == "." {
+		return rebuild(ctx, fsys, root, opts)
+	}
+
+	excludes, err := compilePatterns(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	includes, err := compilePatterns(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	b := newBuilder(ctx, fsys, opts)
+	b.excludes, b.includes = excludes, includes
+	defer b.cancel()
+
+	info, err := fs.Stat(fsys, opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error accessing path %s: %w", opts.Path, err)
+	}
+	ignores, ignoreBase := b.rootIgnores()
+	v := visit{name: opts.Path, path: opts.Path, rel: ".", ignoreBase: ignoreBase, ignores: ignores}
+
+	// Descend through the directories of the tree towards rel. The node
+	// to rescan is rel itself, or its topmost directory missing from the
+	// tree, which a filter may have dropped for having no matches.
+	var dirs []scannedDir
+	node := root
+	for _, name := range strings.Split(rel, "/") {
+		c := b.prepare(v, info)
+		if c == nil || c.node.Type != Directory || node.Type != Directory || node.Cycle || node.Error != "" {
+			return nil, nil
+		}
+		usage := node.DiskUsage
+		for _, child := range node.Children {
+			usage -= child.DiskUsage
+		}
+		dirs = append(dirs, scannedDir{node: node, name: c.name, matched: c.matched, usage: usage})
+
+		v = c.v
+		v.name = c.name
+		v.parents = &ancestor{key: b.dirKey(c.name, c.info), parent: v.parents}
+		v.ignores = b.loadIgnoreRules(v.ignores, c.name, v.ignoreBase)
+		v = b.child(v, name)
+
+		node = childNamed(node, name)
+		if node == nil {
+			break
+		}
+		if info, err = lstat(fsys, v.name); err != nil {
+			break
+		}
+	}
+
+	old := node
+	var current *Node
+	if info, err := lstat(fsys, v.name); err == nil {
+		if current, _, err = b.buildTreeRecursive(v, info); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error accessing path %s: %w", v.path, err)
+	}
+
+	var changes []Change
+	switch {
+	case old == nil && current == nil:
+		return nil, b.partialError()
+	case old == nil:
+		changes = append(changes, Change{Kind: Added, Path: v.rel, New: current})
+	case current == nil:
+		changes = append(changes, Change{Kind: Removed, Path: v.rel, Old: old})
+	default:
+		diffNodes(&changes, v.rel, old, current, DiffOptions{})
+	}
+
+	// Put the node in place, dropping directories that were only kept
+	// for it when it no longer matches the filter
+	name := path.Base(v.rel)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		setChild(dir.node, name, current)
+		if i == 0 || current != nil || dir.matched || len(dir.node.Children) > 0 {
+			break
+		}
+		changes = []Change{{Kind: Removed, Path: path.Dir(v.rel), Old: dir.node}}
+		v.rel, name = path.Dir(v.rel), dir.node.Name
+		dirs = dirs[:i]
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		b.resort(dirs[i])
+	}
+	return changes, b.partialError()
+}
+
+// rebuild rescans the whole tree and replaces root with the result
+func rebuild(ctx context.Context, fsys fs.FS, root *Node, opts *BuildOptions) ([]Change, error) {
+	current, err := buildTree(ctx, fsys, opts)
+	var partialErr *PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		return nil, err
+	}
+	old := *root
+	*root = *current
+	return Diff(&old, root), err
+}
+
+// partialError returns the *PartialError describing paths that could not
+// be read, or nil if there are none
+func (b *builder) partialError() error {
+	if len(b.failures) == 0 {
+		return nil
+	}
+	sort.Slice(b.failures, func(i, j int) bool { return b.failures[i].Path < b.failures[j].Path })
+	return &PartialError{Failures: b.failures}
+}
+
+// childNamed returns the child of node called name, or nil
+func childNamed(node *Node, name string) *Node {
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// setChild replaces the child of dir called name with child, adding it if
+// there is none and removing it if child is nil
+func setChild(dir *Node, name string, child *Node) {
+	for i, existing := range dir.Children {
+		if existing.Name != name {
+			continue
+		}
+		if child == nil {
+			dir.Children = append(dir.Children[:i], dir.Children[i+1:]...)
+		} else {
+			dir.Children[i] = child
+		}
+		return
+	}
+	if child != nil {
+		dir.Children = append(dir.Children, child)
+	}
+}
+
+// resort recomputes the totals of a directory after one of its children
+// changed, and orders its children the way buildChildren does
+func (b *builder) resort(dir scannedDir) {
+	node := dir.node
+	node.DiskUsage = dir.usage
+	sumChildren(node, make([]hardLinks, len(node.Children)))
+
+	entries := make([]sortEntry, len(node.Children))
+	for i, child := range node.Children {
+		entries[i] = sortEntry{node: child, modTime: b.modTime(dir.name, child)}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].node.Name < entries[j].node.Name })
+	sortChildren(entries, b.opts)
+	for i, entry := range entries {
+		node.Children[i] = entry.node
+	}
+}
+
+// modTime returns the modification time children are sorted by, read
+// from the file system unless metadata was collected
+func (b *builder) modTime(dir string, child *Node) time.Time {
+	if b.opts.SortBy != SortByModTime {
+		return time.Time{}
+	}
+	if child.ModTime != nil {
+		return *child.ModTime
+	}
+	if info, err := lstat(b.fsys, b.join(dir, child.Name)); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,175 @@
+package tree
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// TestUpdateFS tests that updating a tree after a change gives the tree a
+// new scan would build
+func TestUpdateFS(t *testing.T) {
+	files := func() fstest.MapFS {
+		return fstest.MapFS{
+			"a.txt":           {Data: []byte("a")},
+			"docs/guide.md":   {Data: []byte("guide")},
+			"docs/api/x.md":   {Data: []byte("x")},
+			"src/main.go":     {Data: []byte("package main")},
+			"vendor/lib/x.go": {Data: []byte("package lib")},
+			"logs/.gitignore": {Data: []byte("*.log\n")},
+			"logs/run.log":    {Data: []byte("log")},
+			"logs/keep.txt":   {Data: []byte("keep")},
+		}
+	}
+	filter, err := ParseFilter("-name *.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     BuildOptions
+		change   func(fstest.MapFS)
+		rel      string
+		expected []ChangeKind
+	}{
+		{
+			name:     "Add file",
+			change:   func(fsys fstest.MapFS) { fsys["docs/new.md"] = &fstest.MapFile{Data: []byte("new")} },
+			rel:      "docs/new.md",
+			expected: []ChangeKind{Added},
+		},
+		{
+			name:     "Add nested directories",
+			change:   func(fsys fstest.MapFS) { fsys["new/deep/x.txt"] = &fstest.MapFile{Data: []byte("x")} },
+			rel:      "new/deep/x.txt",
+			expected: []ChangeKind{Added},
+		},
+		{
+			name:     "Modify file",
+			change:   func(fsys fstest.MapFS) { fsys["docs/api/x.md"].Data = []byte("longer") },
+			rel:      "docs/api/x.md",
+			expected: []ChangeKind{Modified},
+		},
+		{
+			name:     "Remove file",
+			change:   func(fsys fstest.MapFS) { delete(fsys, "src/main.go") },
+			rel:      "src/main.go",
+			expected: []ChangeKind{Removed},
+		},
+		{
+			name: "Remove directory",
+			change: func(fsys fstest.MapFS) {
+				delete(fsys, "docs/guide.md")
+				delete(fsys, "docs/api/x.md")
+			},
+			rel:      "docs",
+			expected: []ChangeKind{Removed},
+		},
+		{
+			name: "Replace file with directory",
+			change: func(fsys fstest.MapFS) {
+				delete(fsys, "a.txt")
+				fsys["a.txt/inner"] = &fstest.MapFile{}
+			},
+			rel:      "a.txt",
+			expected: []ChangeKind{TypeChanged},
+		},
+		{
+			name:     "Excluded path",
+			opts:     BuildOptions{Exclude: []Pattern{{Kind: DoubleStarPattern, Expr: "vendor/**"}}},
+			change:   func(fsys fstest.MapFS) { fsys["vendor/lib/y.go"] = &fstest.MapFile{} },
+			rel:      "vendor/lib/y.go",
+			expected: nil,
+		},
+		{
+			name:     "Beyond maximum depth",
+			opts:     BuildOptions{MaxDepth: 1},
+			change:   func(fsys fstest.MapFS) { fsys["docs/api/y.md"] = &fstest.MapFile{Data: []byte("y")} },
+			rel:      "docs/api/y.md",
+			expected: nil,
+		},
+		{
+			name:     "Ignored file",
+			opts:     BuildOptions{GitIgnore: true},
+			change:   func(fsys fstest.MapFS) { fsys["logs/other.log"] = &fstest.MapFile{} },
+			rel:      "logs/other.log",
+			expected: nil,
+		},
+		{
+			name:     "Ignore file changed",
+			opts:     BuildOptions{GitIgnore: true},
+			change:   func(fsys fstest.MapFS) { fsys["logs/.gitignore"].Data = []byte("*.txt\n") },
+			rel:      "logs/.gitignore",
+			expected: []ChangeKind{Removed, Added},
+		},
+		{
+			name:     "Filter match added",
+			opts:     BuildOptions{Filter: filter},
+			change:   func(fsys fstest.MapFS) { fsys["src/readme.md"] = &fstest.MapFile{} },
+			rel:      "src/readme.md",
+			expected: []ChangeKind{Added},
+		},
+		{
+			name:     "Last filter match removed",
+			opts:     BuildOptions{Filter: filter},
+			change:   func(fsys fstest.MapFS) { delete(fsys, "docs/api/x.md") },
+			rel:      "docs/api/x.md",
+			expected: []ChangeKind{Removed},
+		},
+		{
+			name:     "Sorted by size",
+			opts:     BuildOptions{SortBy: SortBySize, SortDescending: true},
+			change:   func(fsys fstest.MapFS) { fsys["a.txt"].Data = make([]byte, 100) },
+			rel:      "a.txt",
+			expected: []ChangeKind{Modified},
+		},
+		{
+			name:     "Root",
+			change:   func(fsys fstest.MapFS) { delete(fsys, "a.txt") },
+			rel:      ".",
+			expected: []ChangeKind{Removed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.IncludeFiles = true
+			if opts.MaxDepth == 0 {
+				opts.MaxDepth = -1
+			}
+			fsys := files()
+			root, err := BuildTreeFS(fsys, opts)
+			if err != nil {
+				t.Fatalf("BuildTreeFS returned error: %v", err)
+			}
+
+			tt.change(fsys)
+			changes, err := UpdateFS(context.Background(), fsys, root, opts, tt.rel)
+			if err != nil {
+				t.Fatalf("UpdateFS returned error: %v", err)
+			}
+			var kinds []ChangeKind
+			for _, c := range changes {
+				kinds = append(kinds, c.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.expected) {
+				t.Errorf("UpdateFS() changes = %+v, want kinds %v", changes, tt.expected)
+			}
+
+			expected, err := BuildTreeFS(fsys, opts)
+			if err != nil {
+				t.Fatalf("BuildTreeFS returned error: %v", err)
+			}
+			if !reflect.DeepEqual(root, expected) {
+				t.Errorf("Updated tree\n%s\nwant\n%s", dumpTree(root), dumpTree(expected))
+			}
+		})
+	}
+
+	if _, err := UpdateFS(context.Background(), files(), &Node{}, BuildOptions{}, "../x"); err == nil {
+		t.Error("Expected error for invalid path")
+	}
+}
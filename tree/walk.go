@@ -0,0 +1,315 @@
+package tree
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// Visitor receives the nodes of a tree as WalkTree discovers them
+type Visitor interface {
+	// Enter is called for every node, parents before their children and
+	// siblings in order. Children is always empty and the totals of a
+	// directory are not known yet. last reports whether the node is the
+	// last child of its parent.
+	Enter(node *Node, depth int, last bool) error
+	// Leave is called for every node after its descendants. The totals of
+	// a directory (Size, DiskUsage, FileCount, DirCount, MaxDepth) are set.
+	Leave(node *Node, depth int) error
+}
+
+// WalkTree scans the file system like BuildTreeContext, but hands nodes
+// to v as they are discovered instead of building the tree. Only the
+// directories on the path to the current node and the next kept sibling
+// of each are held in memory, so it suits trees too large to materialise;
+// below directories that do not match the filter, the kept nodes are held
+// until the directory is known to be kept. Directories are read once and
+// one at a time, and sorting by size is not supported since directory
+// totals are only known once their children have been visited.
+func WalkTree(ctx context.Context, opts BuildOptions, v Visitor) error {
+	return walkTree(ctx, osFS{}, &opts, v)
+}
+
+// WalkTreeFS is the fs.FS variant of WalkTree, see BuildTreeFS
+func WalkTreeFS(ctx context.Context, fsys fs.FS, opts BuildOptions, v Visitor) error {
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if !fs.ValidPath(opts.Path) {
+		return fmt.Errorf("error accessing path %s: %w", opts.Path, fs.ErrInvalid)
+	}
+	return walkTree(ctx, fsys, &opts, v)
+}
+
+// walkTree walks the tree rooted at opts.Path in fsys
+func walkTree(ctx context.Context, fsys fs.FS, opts *BuildOptions, v Visitor) error {
+	if opts.SortBy == SortBySize || opts.SortBy == SortByDiskUsage {
+		return fmt.Errorf("sorting by size is not supported while walking")
+	}
+
+	info, err := fs.Stat(fsys, opts.Path)
+	if err != nil {
+		return fmt.Errorf("error accessing path %s: %w", opts.Path, err)
+	}
+
+	excludes, err := compilePatterns(opts.Exclude)
+	if err != nil {
+		return err
+	}
+	includes, err := compilePatterns(opts.Include)
+	if err != nil {
+		return err
+	}
+
+	b := newBuilder(ctx, fsys, opts)
+	b.excludes, b.includes = excludes, includes
+	defer b.cancel()
+
+	ignores, ignoreBase := b.rootIgnores()
+	root := b.prepare(visit{
+		name:       opts.Path,
+		path:       opts.Path,
+		rel:        ".",
+		ignoreBase: ignoreBase,
+		ignores:    ignores,
+	}, info)
+	if root == nil {
+		return nil
+	}
+
+	w := &walker{b: b, visitor: v}
+	s, err := w.start(root)
+	if err != nil {
+		return err
+	}
+	if s != nil {
+		if err := w.finish(s, true); err != nil {
+			return err
+		}
+	}
+
+	if len(b.unscanned) > 0 || len(b.failures) > 0 {
+		sort.Strings(b.unscanned)
+		sort.Slice(b.failures, func(i, j int) bool { return b.failures[i].Path < b.failures[j].Path })
+		return &PartialError{Err: ctx.Err(), Unscanned: b.unscanned, Failures: b.failures}
+	}
+	return nil
+}
+
+// walker hands the nodes found by a builder to a Visitor
+type walker struct {
+	b       *builder
+	visitor Visitor
+}
+
+// sibling is a kept node waiting to be entered until it is known whether
+// it is the last child of its parent
+type sibling struct {
+	c       *candidate
+	entries []fs.DirEntry // entries of a directory still to be walked
+	key     fileKey       // key of the directory, for cycle detection
+	events  *recorder     // events of a directory walked ahead, if any
+	links   hardLinks     // hard link set, once known
+}
+
+// start decides whether the candidate c is kept, reading the entries of
+// directories. A directory that does not match the filter is only kept
+// if something below it is, so it is walked right away with its events
+// recorded. It returns nil if c is left out of the tree.
+func (w *walker) start(c *candidate) (*sibling, error) {
+	node := c.node
+	if node.Type != Directory || c.info == nil {
+		if w.b.opts.Hash != "" && node.Type == File && c.info != nil && !w.b.beyondDepth(c.v.depth) {
+			if kept, err := w.b.hashFile(c); kept == nil {
+				return nil, err
+			}
+		}
+		s := &sibling{c: c}
+		if c.info != nil {
+			s.links = w.b.hardLinksOf(node, c.info)
+		}
+		return s, nil
+	}
+
+	entries, key, leaf, err := w.b.openDir(c)
+	if err != nil {
+		return nil, err
+	}
+	if leaf {
+		return &sibling{c: c}, nil
+	}
+	if entries == nil {
+		return nil, nil
+	}
+	s := &sibling{c: c, entries: entries, key: key}
+	if c.matched {
+		return s, nil
+	}
+
+	visitor, events := w.visitor, &recorder{}
+	w.visitor = events
+	kept, err := w.descend(s, false)
+	w.visitor = visitor
+	if err != nil || !kept {
+		return nil, err
+	}
+	s.events = events
+	return s, nil
+}
+
+// finish enters the kept node s with the given last flag, then visits its
+// descendants and leaves it
+func (w *walker) finish(s *sibling, last bool) error {
+	if s.events != nil {
+		return s.events.replay(w.visitor, last)
+	}
+	_, err := w.descend(s, last)
+	return err
+}
+
+// descend enters s, visits its children and leaves it. A kept child is
+// held back until the next kept sibling, or the end of the directory,
+// tells whether it is the last one. It reports whether any child was kept.
+func (w *walker) descend(s *sibling, last bool) (bool, error) {
+	c, node := s.c, s.c.node
+	if err := w.visitor.Enter(node, c.v.depth, last); err != nil {
+		return false, err
+	}
+	if s.entries == nil {
+		return false, w.visitor.Leave(node, c.v.depth)
+	}
+
+	dir := c.v
+	dir.name = c.name
+	dir.parents = &ancestor{key: s.key, parent: dir.parents}
+	dir.ignores = w.b.loadIgnoreRules(dir.ignores, c.name, dir.ignoreBase)
+	children, err := w.b.prepareChildren(dir, s.entries)
+	if err != nil {
+		return false, err
+	}
+	s.entries = nil
+
+	// Children below the maximum depth only count towards the totals
+	visitor := w.visitor
+	if w.b.beyondDepth(c.v.depth + 1) {
+		w.visitor = discard{}
+		defer func() { w.visitor = visitor }()
+	}
+
+	node.Size, node.FileCount, node.DirCount, node.MaxDepth = 0, 0, 0, 0
+	var pending *sibling
+	for _, child := range children {
+		next, err := w.start(child)
+		if err != nil {
+			return false, err
+		}
+		if next == nil {
+			continue
+		}
+		if pending != nil {
+			if err := w.finish(pending, false); err != nil {
+				return false, err
+			}
+			s.links = addChild(node, pending.c.node, pending.links, s.links)
+		}
+		pending = next
+	}
+	if pending == nil {
+		return false, visitor.Leave(node, c.v.depth)
+	}
+	if err := w.finish(pending, true); err != nil {
+		return false, err
+	}
+	s.links = addChild(node, pending.c.node, pending.links, s.links)
+	return true, visitor.Leave(node, c.v.depth)
+}
+
+// discard is a Visitor ignoring the nodes below the maximum depth
+type discard struct{}
+
+// Enter implements Visitor
+func (discard) Enter(*Node, int, bool) error { return nil }
+
+// Leave implements Visitor
+func (discard) Leave(*Node, int) error { return nil }
+
+// recorder is a Visitor keeping the events of a directory walked before
+// it is known whether it is the last child of its parent. Only the kept
+// nodes below the directory are held.
+type recorder struct {
+	events []event
+}
+
+// event is a call recorded by a recorder
+type event struct {
+	node  *Node
+	depth int
+	last  bool
+	leave bool
+}
+
+// Enter implements Visitor
+func (r *recorder) Enter(node *Node, depth int, last bool) error {
+	r.events = append(r.events, event{node: node, depth: depth, last: last})
+	return nil
+}
+
+// Leave implements Visitor
+func (r *recorder) Leave(node *Node, depth int) error {
+	r.events = append(r.events, event{node: node, depth: depth, leave: true})
+	return nil
+}
+
+// replay hands the recorded events to v. The first event enters the
+// recorded directory itself and gets the given last flag.
+func (r *recorder) replay(v Visitor, last bool) error {
+	for i, e := range r.events {
+		var err error
+		switch {
+		case e.leave:
+			err = v.Leave(e.node, e.depth)
+		case i == 0:
+			err = v.Enter(e.node, e.depth, last)
+		default:
+			err = v.Enter(e.node, e.depth, e.last)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareChildren prepares the candidates for the entries of a directory,
+// in the order selected by the sort options
+func (b *builder) prepareChildren(dir visit, entries []fs.DirEntry) ([]*candidate, error) {
+	var sorted []sortEntry
+	for _, entry := range entries {
+		entryInfo, err := entry.Info()
+		if err != nil {
+			node, err := b.entryFailed(dir, entry, err)
+			if err != nil {
+				return nil, err
+			}
+			if node != nil {
+				// Entries without status are kept as leaves
+				c := &candidate{v: b.child(dir, entry.Name()), node: node, matched: true}
+				sorted = append(sorted, sortEntry{node: node, candidate: c})
+			}
+			continue
+		}
+
+		c := b.prepare(b.child(dir, entryInfo.Name()), entryInfo)
+		if c != nil {
+			sorted = append(sorted, sortEntry{node: c.node, modTime: entryInfo.ModTime(), candidate: c})
+		}
+	}
+	sortChildren(sorted, b.opts)
+
+	children := make([]*candidate, len(sorted))
+	for i, entry := range sorted {
+		children[i] = entry.candidate
+	}
+	return children, nil
+}
@@ -0,0 +1,213 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// treeRecorder is a Visitor that rebuilds the tree it is walked over
+type treeRecorder struct {
+	t     *testing.T
+	root  *Node
+	stack []*Node
+	last  map[*Node]bool
+}
+
+// Enter implements Visitor
+func (r *treeRecorder) Enter(node *Node, depth int, last bool) error {
+	if depth != len(r.stack) {
+		r.t.Errorf("Enter(%s) at depth %d, want %d", node.Path, depth, len(r.stack))
+	}
+	if len(node.Children) != 0 {
+		r.t.Errorf("Enter(%s) with children", node.Path)
+	}
+	if len(r.stack) == 0 {
+		r.root = node
+	} else {
+		parent := r.stack[len(r.stack)-1]
+		parent.Children = append(parent.Children, node)
+	}
+	r.last[node] = last
+	r.stack = append(r.stack, node)
+	return nil
+}
+
+// Leave implements Visitor
+func (r *treeRecorder) Leave(node *Node, depth int) error {
+	if top := r.stack[len(r.stack)-1]; top != node || depth != len(r.stack)-1 {
+		r.t.Errorf("Leave(%s) at depth %d, want %s at depth %d", node.Path, depth, top.Path, len(r.stack)-1)
+	}
+	r.stack = r.stack[:len(r.stack)-1]
+	for i, child := range node.Children {
+		if r.last[child] != (i == len(node.Children)-1) {
+			r.t.Errorf("Enter(%s) reported last = %v", child.Path, r.last[child])
+		}
+	}
+	return nil
+}
+
+// TestWalkTreeFS tests that walking visits the same tree as building
+func TestWalkTreeFS(t *testing.T) {
+	now := time.Now()
+	fsys := failingFS{
+		MapFS: fstest.MapFS{
+			"a.txt":             {Data: []byte("a")},
+			"src/main.go":       {Data: []byte("package main")},
+			"src/util/util.go":  {Data: []byte("package util")},
+			"src/util/old.txt":  {Data: []byte("old"), ModTime: now.Add(-48 * time.Hour)},
+			"docs/readme.md":    {Data: []byte("# docs")},
+			"docs/img/logo.png": {Data: []byte("png")},
+			"empty":             {Mode: fs.ModeDir | 0o755},
+			"locked/secret.txt": {Data: []byte("secret")},
+			"z/deep/deeper/x.c": {Data: []byte("int x;")},
+		},
+		unreadable: map[string]bool{"locked": true},
+	}
+
+	filter := func(expr string) *Filter {
+		f, err := ParseFilter(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	tests := []struct {
+		name string
+		opts BuildOptions
+	}{
+		{"Default", BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: RecordOnError}},
+		{"Directories", BuildOptions{MaxDepth: -1, ErrorPolicy: RecordOnError}},
+		{"MaxDepth", BuildOptions{MaxDepth: 1, IncludeFiles: true, ErrorPolicy: RecordOnError}},
+		{"Skip", BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: SkipOnError}},
+		{"Filter", BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: SkipOnError, Filter: filter("-name *.go")}},
+		{"FilterLast", BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: SkipOnError, Filter: filter("-name a.txt -o -name *.go")}},
+		{"FilterDirs", BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: RecordOnError, Filter: filter("-type d -name img")}},
+		{"FilterNone", BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: SkipOnError, Filter: filter("-name nothing")}},
+		{"Sorted", BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: RecordOnError, SortBy: SortByName, SortDescending: true, FilesFirst: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, buildErr := BuildTreeFS(fsys, tt.opts)
+
+			r := &treeRecorder{t: t, last: map[*Node]bool{}}
+			walkErr := WalkTreeFS(context.Background(), fsys, tt.opts, r)
+
+			if !reflect.DeepEqual(walkErr, buildErr) {
+				t.Errorf("WalkTreeFS error = %v, want %v", walkErr, buildErr)
+			}
+			if len(r.stack) != 0 {
+				t.Errorf("%d nodes were not left", len(r.stack))
+			}
+			if !reflect.DeepEqual(r.root, expected) {
+				t.Errorf("WalkTreeFS visited\n%s\nwant\n%s", dumpTree(r.root), dumpTree(expected))
+			}
+		})
+	}
+}
+
+// TestWalkTreeFSErrors tests errors reported by WalkTreeFS
+func TestWalkTreeFSErrors(t *testing.T) {
+	fsys := fstest.MapFS{"dir/file.txt": {Data: []byte("x")}}
+	r := &treeRecorder{t: t, last: map[*Node]bool{}}
+
+	err := WalkTreeFS(context.Background(), fsys, BuildOptions{MaxDepth: -1, SortBy: SortBySize}, r)
+	if err == nil {
+		t.Error("Expected an error when sorting by size")
+	}
+
+	err = WalkTreeFS(context.Background(), fsys, BuildOptions{Path: "missing", MaxDepth: -1}, r)
+	if err == nil {
+		t.Error("Expected an error for a missing root")
+	}
+
+	stop := errors.New("stop")
+	err = WalkTreeFS(context.Background(), fsys, BuildOptions{MaxDepth: -1, IncludeFiles: true}, stopVisitor{stop})
+	if !errors.Is(err, stop) {
+		t.Errorf("WalkTreeFS error = %v, want the visitor's error", err)
+	}
+}
+
+// countingFS counts the directory reads of a MapFS
+type countingFS struct {
+	fstest.MapFS
+	reads map[string]int
+}
+
+// ReadDir implements fs.ReadDirFS
+func (c countingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.reads[name]++
+	return c.MapFS.ReadDir(name)
+}
+
+// TestWalkTreeFSReadsOnce tests that deciding which node is last does not
+// read directories ahead of the walk
+func TestWalkTreeFSReadsOnce(t *testing.T) {
+	fsys := countingFS{
+		MapFS: fstest.MapFS{
+			"a/b/c/main.go": {Data: []byte("package main")},
+			"a/b/d/x.txt":   {Data: []byte("x")},
+			"a/e/util.go":   {Data: []byte("package util")},
+			"f/g/y.txt":     {Data: []byte("y")},
+		},
+		reads: map[string]int{},
+	}
+	filter, err := ParseFilter("-name *.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, policy := range []ErrorPolicy{RecordOnError, SkipOnError} {
+		clear(fsys.reads)
+		r := &treeRecorder{t: t, last: map[*Node]bool{}}
+		opts := BuildOptions{MaxDepth: -1, IncludeFiles: true, ErrorPolicy: policy, Filter: filter}
+		if err := WalkTreeFS(context.Background(), fsys, opts, r); err != nil {
+			t.Fatalf("WalkTreeFS returned error: %v", err)
+		}
+		for name, reads := range fsys.reads {
+			if reads != 1 {
+				t.Errorf("Directory %s read %d times with policy %v", name, reads, policy)
+			}
+		}
+		if len(fsys.reads) != 8 {
+			t.Errorf("Read %d directories, want 8: %v", len(fsys.reads), fsys.reads)
+		}
+	}
+}
+
+// stopVisitor fails on the first file
+type stopVisitor struct{ err error }
+
+// Enter implements Visitor
+func (v stopVisitor) Enter(node *Node, depth int, last bool) error {
+	if node.Type == File {
+		return v.err
+	}
+	return nil
+}
+
+// Leave implements Visitor
+func (v stopVisitor) Leave(node *Node, depth int) error { return nil }
+
+// dumpTree lists the paths and totals of a tree for error messages
+func dumpTree(node *Node) string {
+	if node == nil {
+		return "<nil>"
+	}
+	var s string
+	var walk func(*Node, string)
+	walk = func(n *Node, indent string) {
+		s += indent + n.Path + " " + string(n.Type) + " " + n.Error + "\n"
+		for _, child := range n.Children {
+			walk(child, indent+"  ")
+		}
+	}
+	walk(node, "")
+	return s
+}
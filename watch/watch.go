@@ -0,0 +1,242 @@
+// Package watch keeps a directory tree up to date while the file system
+// changes, using the notifications of the operating system
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Maxim-Ba/dir-tree/tree"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the quiet period used when Options.Debounce is zero
+const DefaultDebounce = 100 * time.Millisecond
+
+// MaxDebounces is the number of quiet periods after which a burst is
+// applied even if events keep arriving
+const MaxDebounces = 10
+
+// Options controls how a Watcher builds and updates its tree
+type Options struct {
+	tree.BuildOptions
+
+	// Debounce is how long the file system must stay quiet before a burst
+	// of events is applied to the tree. Every event restarts the period,
+	// but a burst is applied at the latest MaxDebounces periods after its
+	// first event.
+	Debounce time.Duration
+}
+
+// Event is a change applied to the watched tree
+type Event struct {
+	Time time.Time `json:"time"`
+	tree.Change
+}
+
+// Handler is called with the updated tree and the events of every burst
+// that changed it. The tree is only valid until the handler returns.
+type Handler func(root *tree.Node, events []Event) error
+
+// Watcher holds a directory tree and the notifications keeping it current
+type Watcher struct {
+	opts     Options
+	root     *tree.Node
+	notifier *fsnotify.Watcher
+}
+
+// New builds the tree described by opts and starts watching every
+// directory in it. Excluded directories, and those at the maximum depth,
+// are not watched. Under the skip and record error policies a watcher is
+// returned along with the *tree.PartialError of the initial scan.
+func New(ctx context.Context, opts Options) (*Watcher, error) {
+	root, buildErr := tree.BuildTreeContext(ctx, opts.BuildOptions)
+	var partialErr *tree.PartialError
+	if buildErr != nil && !errors.As(buildErr, &partialErr) {
+		return nil, buildErr
+	}
+
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error watching %s: %w", opts.Path, err)
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	w := &Watcher{opts: opts, root: root, notifier: notifier}
+	if err := w.watch(root, 0); err != nil {
+		notifier.Close()
+		return nil, err
+	}
+	return w, buildErr
+}
+
+// Root returns the watched tree. It must not be used while Run is running,
+// except from its handler.
+func (w *Watcher) Root() *tree.Node {
+	return w.root
+}
+
+// Run applies file system events to the tree until ctx is done, calling
+// handle after every burst of events that changed it. Errors of handle
+// and of the notifications stop the watcher; paths that cannot be read
+// are handled according to the error policy.
+func (w *Watcher) Run(ctx context.Context, handle Handler) error {
+	pending := map[string]bool{}
+	timer := time.NewTimer(w.opts.Debounce)
+	timer.Stop()
+
+	// schedule adds rel to the pending paths and restarts the quiet
+	// period, without waiting past the deadline of the burst
+	var deadline time.Time
+	schedule := func(rel string) {
+		if len(pending) == 0 {
+			deadline = time.Now().Add(MaxDebounces * w.opts.Debounce)
+		}
+		pending[rel] = true
+		timer.Reset(min(w.opts.Debounce, time.Until(deadline)))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-w.notifier.Events:
+			if !ok {
+				return nil
+			}
+			if rel, ok := w.rel(event.Name); ok {
+				schedule(rel)
+			}
+
+		case err, ok := <-w.notifier.Errors:
+			if !ok {
+				return nil
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return fmt.Errorf("error watching %s: %w", w.opts.Path, err)
+			}
+			// Events were lost, so only a full rescan is reliable
+			schedule(".")
+
+		case <-timer.C:
+			events, err := w.apply(ctx, pending)
+			pending = map[string]bool{}
+			if err != nil {
+				return err
+			}
+			if len(events) > 0 {
+				if err := handle(w.root, events); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// Close stops watching the file system
+func (w *Watcher) Close() error {
+	return w.notifier.Close()
+}
+
+// rel returns the slash separated path of name relative to the root
+func (w *Watcher) rel(name string) (string, bool) {
+	rel, err := filepath.Rel(w.opts.Path, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// apply updates the tree for the changed paths and watches the
+// directories that were added
+func (w *Watcher) apply(ctx context.Context, pending map[string]bool) ([]Event, error) {
+	var events []Event
+	now := time.Now()
+	for _, rel := range rescanned(pending) {
+		changes, err := tree.Update(ctx, w.root, w.opts.BuildOptions, rel)
+		var partialErr *tree.PartialError
+		if err != nil && !errors.As(err, &partialErr) {
+			return nil, err
+		}
+		for _, change := range changes {
+			if change.New != nil && change.Kind != tree.Modified {
+				if err := w.watch(change.New, depth(change.Path)); err != nil {
+					return nil, err
+				}
+			}
+			events = append(events, Event{Time: now, Change: change})
+		}
+	}
+	return events, nil
+}
+
+// rescanned returns the changed paths to rescan, in order. Paths below
+// another changed path are rescanned along with it.
+func rescanned(pending map[string]bool) []string {
+	paths := make([]string, 0, len(pending))
+	for rel := range pending {
+		paths = append(paths, rel)
+	}
+	// A directory sorts before the paths below it, though not always
+	// right before them: "a b" comes between "a" and "a/c"
+	sort.Strings(paths)
+
+	var result []string
+	kept := map[string]bool{}
+	for _, rel := range paths {
+		if !covered(kept, rel) {
+			result = append(result, rel)
+			kept[rel] = true
+		}
+	}
+	return result
+}
+
+// covered reports whether rel or one of its directories is in rescanned
+func covered(rescanned map[string]bool, rel string) bool {
+	for {
+		if rescanned[rel] {
+			return true
+		}
+		if rel == "." {
+			return false
+		}
+		rel = path.Dir(rel)
+	}
+}
+
+// depth returns the depth below the root of the node at rel
+func depth(rel string) int {
+	if rel == "." {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+// watch adds the directories of the subtree node, found at depth, to the
+// watched paths. Directories whose children would be beyond the maximum
+// depth, and links that loop back to an ancestor, are left out.
+func (w *Watcher) watch(node *tree.Node, depth int) error {
+	if node.Type != tree.Directory || node.Cycle || node.Error != "" || (w.opts.MaxDepth != -1 && depth >= w.opts.MaxDepth) {
+		return nil
+	}
+	// A directory removed since it was scanned has its own event coming
+	if err := w.notifier.Add(node.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error watching %s: %w", node.Path, err)
+	}
+	for _, child := range node.Children {
+		if err := w.watch(child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Maxim-Ba/dir-tree/tree"
+)
+
+// TestWatcher tests that file system changes are applied to the tree
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("keep.txt", "keep")
+	write("docs/guide.md", "guide")
+	write("vendor/lib.go", "package lib")
+
+	opts := Options{
+		BuildOptions: tree.BuildOptions{
+			Path:         dir,
+			MaxDepth:     -1,
+			IncludeFiles: true,
+			Exclude:      []tree.Pattern{{Kind: tree.GlobPattern, Expr: "vendor"}},
+		},
+		Debounce: 20 * time.Millisecond,
+	}
+	w, err := New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bursts := make(chan []Event)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(root *tree.Node, events []Event) error {
+			bursts <- events
+			return nil
+		})
+	}()
+
+	// next waits for the events of the next burst
+	next := func() []string {
+		t.Helper()
+		select {
+		case events := <-bursts:
+			var result []string
+			for _, event := range events {
+				result = append(result, string(event.Kind)+" "+event.Path)
+			}
+			return result
+		case <-time.After(5 * time.Second):
+			t.Fatal("No events received")
+			return nil
+		}
+	}
+
+	// Excluded paths are not reported, so the burst only holds the new files
+	write("vendor/new.go", "package lib")
+	write("src/main.go", "package main")
+	write("src/util.go", "package main")
+	if events, expected := next(), []string{"added src"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events = %v, want %v", events, expected)
+	}
+
+	// Files in new directories are watched too
+	write("src/util.go", "package main // changed")
+	if events, expected := next(), []string{"modified src/util.go"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events = %v, want %v", events, expected)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "docs")); err != nil {
+		t.Fatal(err)
+	}
+	if events, expected := next(), []string{"removed docs"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events = %v, want %v", events, expected)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+
+	expected, err := tree.BuildTree(opts.BuildOptions)
+	if err != nil {
+		t.Fatalf("BuildTree returned error: %v", err)
+	}
+	if !reflect.DeepEqual(w.Root(), expected) {
+		t.Errorf("Watched tree differs from a new scan")
+	}
+}
+
+// TestWatcherMaxDelay tests that a burst is applied even if events keep
+// arriving
+func TestWatcherMaxDelay(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{
+		BuildOptions: tree.BuildOptions{Path: dir, MaxDepth: -1, IncludeFiles: true},
+		Debounce:     50 * time.Millisecond,
+	}
+	w, err := New(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bursts := make(chan []Event, 1)
+	go w.Run(ctx, func(root *tree.Node, events []Event) error {
+		select {
+		case bursts <- events:
+		default:
+		}
+		return nil
+	})
+
+	// Write more often than the quiet period for far longer than the cap
+	start := time.Now()
+	path := filepath.Join(dir, "busy.txt")
+	for time.Since(start) < 4*time.Second {
+		if err := os.WriteFile(path, []byte(time.Now().String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-bursts:
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("First burst applied after %v", elapsed)
+			}
+			return
+		case <-time.After(opts.Debounce / 5):
+		}
+	}
+	t.Error("No burst applied while events kept arriving")
+}
+
+// TestRescanned tests which changed paths are rescanned
+func TestRescanned(t *testing.T) {
+	tests := []struct {
+		name     string
+		pending  []string
+		expected []string
+	}{
+		{"Separate", []string{"b", "a"}, []string{"a", "b"}},
+		{"Nested", []string{"a", "a/c", "a/c/d"}, []string{"a"}},
+		{"Sibling sorted between", []string{"a", "a b", "a/c"}, []string{"a", "a b"}},
+		{"Prefix only", []string{"ab", "a"}, []string{"a", "ab"}},
+		{"Root", []string{"x", ".", "y/z"}, []string{"."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := map[string]bool{}
+			for _, rel := range tt.pending {
+				pending[rel] = true
+			}
+			if result := rescanned(pending); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("rescanned(%v) = %v, want %v", tt.pending, result, tt.expected)
+			}
+		})
+	}
+}